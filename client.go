@@ -1,6 +1,7 @@
 package k8s_client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	k8sCluster "github.com/vperson/k8s-client/typed/cluster/v1"
@@ -51,6 +52,12 @@ func (c *ClientSet) Kubernetes() k8sCluster.ClusterInterface {
 	return c.k8sCluster
 }
 
+// 通过Lease选主,成为leader后执行onStart,阻塞直到ctx结束或失去leader.
+// 需要readiness探针时使用Kubernetes().LeaderElector
+func (c *ClientSet) RunWithLeaderElection(ctx context.Context, name, namespace string, onStart func(ctx context.Context), onStop func()) error {
+	return c.k8sCluster.RunWithLeaderElection(ctx, name, namespace, onStart, onStop)
+}
+
 // 获取动态client,可深度再定制
 func (c *ClientSet) Discovery() discovery.DiscoveryInterface {
 	if c == nil {
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	k8sCluster "github.com/vperson/k8s-client/typed/cluster/v1"
 	"io/ioutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -65,7 +68,12 @@ func TestKubeRestConfigGetter(t *testing.T) {
 	clusterClient := client.Kubernetes()
 	fileByte, _ := ioutil.ReadFile("D:/Users/fonzie/go/src/xiaomai-sentry/script/dump.sh")
 	file := strings.ReplaceAll(string(fileByte), "\r", "")
-	_, err = clusterClient.Pods(namespace).CopyToPod(ctx, podName, container, bytes.NewReader([]byte(file)), "/usr/local/bin/dump.sh")
+	tmpFile := filepath.Join(os.TempDir(), "dump.sh")
+	if err := ioutil.WriteFile(tmpFile, []byte(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile)
+	_, err = clusterClient.Pods(namespace).CopyToPod(ctx, podName, container, tmpFile, "/usr/local/bin/dump.sh")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -76,7 +84,11 @@ func TestKubeRestConfigGetter(t *testing.T) {
 	t.Log(commands)
 	var stdout bytes.Buffer
 
-	_, err = clusterClient.Pods(namespace).Exec(ctx, podName, container, commands, nil, &stdout)
+	_, err = clusterClient.Pods(namespace).Exec(ctx, podName, k8sCluster.ExecOptions{
+		Container: container,
+		Command:   commands,
+		Stdout:    &stdout,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
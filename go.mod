@@ -4,7 +4,12 @@ go 1.14
 
 require (
 	github.com/coreos/prometheus-operator v0.41.0
+	github.com/go-kit/kit v0.10.0
+	github.com/gorilla/websocket v1.4.0
 	github.com/imdario/mergo v0.3.9 // indirect
+	github.com/prometheus/client_golang v1.6.0
+	github.com/prometheus/common v0.10.0
+	github.com/prometheus/prometheus v1.8.2-0.20200609102542-5d7e3e970602
 	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e // indirect
 	k8s.io/api v0.18.3
 	k8s.io/apimachinery v0.18.3
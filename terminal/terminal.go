@@ -0,0 +1,352 @@
+package terminal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	k8sCluster "github.com/vperson/k8s-client/typed/cluster/v1"
+	"io"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/klog"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// 与xterm.js之间的消息类型
+	OpStdin  = "stdin"
+	OpStdout = "stdout"
+	OpResize = "resize"
+	OpClose  = "close"
+
+	defaultIdleTimeout = 15 * time.Minute
+)
+
+// 优先使用bash,镜像中没有bash时回退到sh
+var defaultCommand = []string{"/bin/sh", "-c", "TERM=xterm-256color; export TERM; [ -x /bin/bash ] && exec /bin/bash || exec /bin/sh"}
+
+// Message 浏览器与网关之间传输的消息
+type Message struct {
+	Op   string `json:"op"`
+	Data string `json:"data,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+}
+
+type Options struct {
+	// 允许进入的namespace,"*"表示全部,为空时拒绝所有请求
+	AllowedNamespaces []string
+	// 超过该时间没有输入则断开会话,默认15分钟
+	IdleTimeout time.Duration
+	// 在容器中启动的命令,默认优先使用bash
+	Command []string
+	// 会话审计记录,每条记录为一行JSON
+	Transcript io.Writer
+	// 校验WebSocket请求的Origin,为空时只允许同源请求
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Handler 通过WebSocket提供进入pod的web终端,
+// 请求参数为namespace,pod,container
+type Handler struct {
+	pods     k8sCluster.PodsGetter
+	opts     Options
+	upgrader websocket.Upgrader
+
+	transcriptMu sync.Mutex
+}
+
+func NewHandler(pods k8sCluster.PodsGetter, opts Options) *Handler {
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = defaultIdleTimeout
+	}
+	if len(opts.Command) == 0 {
+		opts.Command = defaultCommand
+	}
+
+	return &Handler{
+		pods: pods,
+		opts: opts,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     opts.CheckOrigin,
+		},
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	namespace := query.Get("namespace")
+	pod := query.Get("pod")
+	container := query.Get("container")
+	if namespace == "" || pod == "" {
+		http.Error(w, "namespace and pod are required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.allowed(namespace) {
+		http.Error(w, fmt.Sprintf("namespace %s is not allowed", namespace), http.StatusForbidden)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade失败时已经向客户端返回了错误
+		klog.Errorf("upgrade websocket err: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	s := newSession(ctx, cancel, conn, h)
+	s.namespace = namespace
+	s.pod = pod
+	s.container = container
+	s.remoteAddr = r.RemoteAddr
+
+	s.record("open", "")
+	go s.readLoop()
+	go s.idleLoop(h.opts.IdleTimeout)
+
+	_, err = h.pods.Pods(namespace).Exec(ctx, pod, k8sCluster.ExecOptions{
+		Container:         container,
+		Command:           h.opts.Command,
+		Stdin:             s,
+		Stdout:            s,
+		TTY:               true,
+		TerminalSizeQueue: s,
+	})
+	s.flush()
+
+	reason := "exit"
+	if err != nil {
+		reason = err.Error()
+	}
+	s.record("close", reason)
+	s.send(Message{Op: OpClose, Data: reason})
+}
+
+func (h *Handler) allowed(namespace string) bool {
+	for _, ns := range h.opts.AllowedNamespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) writeTranscript(entry transcriptEntry) {
+	if h.opts.Transcript == nil {
+		return
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	h.transcriptMu.Lock()
+	defer h.transcriptMu.Unlock()
+	if _, err := h.opts.Transcript.Write(append(b, '\n')); err != nil {
+		klog.Errorf("write terminal transcript err: %v", err)
+	}
+}
+
+type transcriptEntry struct {
+	Time       time.Time `json:"time"`
+	Session    string    `json:"session"`
+	Namespace  string    `json:"namespace"`
+	Pod        string    `json:"pod"`
+	Container  string    `json:"container,omitempty"`
+	RemoteAddr string    `json:"remoteAddr,omitempty"`
+	Op         string    `json:"op"`
+	Data       string    `json:"data,omitempty"`
+}
+
+// session 实现了Exec所需的stdin,stdout和TerminalSizeQueue
+type session struct {
+	id         string
+	namespace  string
+	pod        string
+	container  string
+	remoteAddr string
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	conn    *websocket.Conn
+	handler *Handler
+
+	writeMu sync.Mutex
+	// 上一次Write末尾不完整的UTF-8字符
+	pending  []byte
+	stdinR   *io.PipeReader
+	stdinW   *io.PipeWriter
+	sizeChan chan remotecommand.TerminalSize
+	activity chan struct{}
+}
+
+func newSession(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, h *Handler) *session {
+	r, w := io.Pipe()
+	return &session{
+		id:       sessionID(),
+		ctx:      ctx,
+		cancel:   cancel,
+		conn:     conn,
+		handler:  h,
+		stdinR:   r,
+		stdinW:   w,
+		sizeChan: make(chan remotecommand.TerminalSize, 1),
+		activity: make(chan struct{}, 1),
+	}
+}
+
+func (s *session) Read(p []byte) (int, error) {
+	return s.stdinR.Read(p)
+}
+
+// Write 只发送完整的UTF-8字符,被拆开的多字节字符留到下一次Write,
+// 避免JSON编码时被替换成U+FFFD
+func (s *session) Write(p []byte) (int, error) {
+	data := append(s.pending, p...)
+	n := utf8Boundary(data)
+	s.pending = append([]byte(nil), data[n:]...)
+	if n == 0 {
+		return len(p), nil
+	}
+
+	s.record(OpStdout, string(data[:n]))
+	if err := s.send(Message{Op: OpStdout, Data: string(data[:n])}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush 发送Exec结束时剩余的字节
+func (s *session) flush() {
+	if len(s.pending) == 0 {
+		return
+	}
+	data := string(s.pending)
+	s.pending = nil
+	s.record(OpStdout, data)
+	s.send(Message{Op: OpStdout, Data: data})
+}
+
+// Next 返回下一次终端大小变化,会话结束时返回nil
+func (s *session) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-s.sizeChan:
+		return &size
+	case <-s.ctx.Done():
+		return nil
+	}
+}
+
+func (s *session) send(msg Message) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteJSON(msg)
+}
+
+func (s *session) readLoop() {
+	defer s.cancel()
+	defer s.stdinW.Close()
+
+	for {
+		var msg Message
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		switch msg.Op {
+		case OpStdin:
+			s.touch()
+			s.record(OpStdin, msg.Data)
+			if _, err := s.stdinW.Write([]byte(msg.Data)); err != nil {
+				return
+			}
+		case OpResize:
+			size := remotecommand.TerminalSize{Width: msg.Cols, Height: msg.Rows}
+			// 只保留最新的终端大小
+			select {
+			case <-s.sizeChan:
+			default:
+			}
+			s.sizeChan <- size
+		case OpClose:
+			return
+		}
+	}
+}
+
+// idleLoop 超过timeout没有输入时结束会话
+func (s *session) idleLoop(timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.activity:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(timeout)
+		case <-timer.C:
+			s.record("idle", timeout.String())
+			s.cancel()
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *session) touch() {
+	select {
+	case s.activity <- struct{}{}:
+	default:
+	}
+}
+
+func (s *session) record(op, data string) {
+	s.handler.writeTranscript(transcriptEntry{
+		Time:       time.Now(),
+		Session:    s.id,
+		Namespace:  s.namespace,
+		Pod:        s.pod,
+		Container:  s.container,
+		RemoteAddr: s.remoteAddr,
+		Op:         op,
+		Data:       data,
+	})
+}
+
+// utf8Boundary 返回b中最后一个不完整的UTF-8字符之前的长度,
+// 非法的字节序列按完整字符处理
+func utf8Boundary(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+func sessionID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
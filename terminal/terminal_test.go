@@ -0,0 +1,170 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"github.com/gorilla/websocket"
+	k8sCluster "github.com/vperson/k8s-client/typed/cluster/v1"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakePods struct {
+	k8sCluster.PodsInterface
+}
+
+func (f *fakePods) Pods(namespace string) k8sCluster.PodsInterface {
+	return f
+}
+
+// 回显一次输入后退出
+func (f *fakePods) Exec(ctx context.Context, podName string, opts k8sCluster.ExecOptions) (int, error) {
+	buf := make([]byte, 64)
+	n, err := opts.Stdin.Read(buf)
+	if err != nil {
+		return -1, err
+	}
+	_, err = opts.Stdout.Write(buf[:n])
+	return 0, err
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	var transcript syncBuffer
+	h := NewHandler(&fakePods{}, Options{
+		AllowedNamespaces: []string{"dev-xiaomai-server"},
+		Transcript:        &transcript,
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "?namespace=dev-xiaomai-server&pod=app"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(Message{Op: OpResize, Rows: 24, Cols: 80}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(Message{Op: OpStdin, Data: "ls\r"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Op != OpStdout || msg.Data != "ls\r" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Op != OpClose {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	if !strings.Contains(transcript.String(), `"op":"stdin","data":"ls\r"`) {
+		t.Fatalf("stdin not recorded: %s", transcript.String())
+	}
+}
+
+// 把多字节字符拆开写入
+type splitPods struct {
+	fakePods
+}
+
+func (f *splitPods) Pods(namespace string) k8sCluster.PodsInterface {
+	return f
+}
+
+func (f *splitPods) Exec(ctx context.Context, podName string, opts k8sCluster.ExecOptions) (int, error) {
+	out := []byte("你好")
+	for _, chunk := range [][]byte{out[:1], out[1:4], out[4:]} {
+		if _, err := opts.Stdout.Write(chunk); err != nil {
+			return -1, err
+		}
+	}
+	return 0, nil
+}
+
+func TestHandler_SplitUTF8(t *testing.T) {
+	h := NewHandler(&splitPods{}, Options{AllowedNamespaces: []string{"*"}})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "?namespace=dev-xiaomai-server&pod=app"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var stdout []string
+	for {
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Op == OpClose {
+			break
+		}
+		stdout = append(stdout, msg.Data)
+	}
+	if strings.Join(stdout, "|") != "你|好" {
+		t.Fatalf("unexpected stdout: %q", stdout)
+	}
+}
+
+func TestUTF8Boundary(t *testing.T) {
+	b := []byte("a你")
+	for i, want := range []int{0, 1, 1, 1, 4} {
+		if got := utf8Boundary(b[:i]); got != want {
+			t.Fatalf("utf8Boundary(%q) = %d, want %d", b[:i], got, want)
+		}
+	}
+	// 非法字节不会被一直保留
+	if got := utf8Boundary([]byte{'a', 0xff}); got != 2 {
+		t.Fatalf("invalid byte boundary = %d", got)
+	}
+}
+
+func TestHandler_NamespaceNotAllowed(t *testing.T) {
+	h := NewHandler(&fakePods{}, Options{AllowedNamespaces: []string{"dev-xiaomai-server"}})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "?namespace=kube-system&pod=app"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("expected dial to fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %v", resp)
+	}
+}
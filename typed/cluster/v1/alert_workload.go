@@ -0,0 +1,184 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+)
+
+const defaultAlertEvents = 10
+
+// RolloutState deployment滚动更新的状态
+type RolloutState string
+
+const (
+	RolloutComplete    RolloutState = "Complete"
+	RolloutProgressing RolloutState = "Progressing"
+	RolloutPaused      RolloutState = "Paused"
+	RolloutFailed      RolloutState = "Failed"
+)
+
+// 告警中用于定位工作负载的label,和kube-state-metrics,cAdvisor的label一致.
+// 没有honor_labels时namespace和pod是采集目标自己的,exported_*才是工作负载的,所以优先使用
+var (
+	alertNamespaceLabels  = []string{"exported_namespace", "namespace"}
+	alertPodLabels        = []string{"exported_pod", "pod", "pod_name"}
+	alertDeploymentLabels = []string{"deployment"}
+	alertReplicaSetLabels = []string{"replicaset"}
+)
+
+type AlertWorkloadOptions struct {
+	// 每个告警返回的最近事件数,默认10
+	MaxEvents int
+}
+
+// AlertWorkload 告警和所属工作负载的关联结果
+type AlertWorkload struct {
+	Alert     map[string]string `json:"alert"`
+	Namespace string            `json:"namespace,omitempty"`
+	Pod       string            `json:"pod,omitempty"`
+	// 所属的控制器不是Deployment时只填写OwnerKind和OwnerName
+	OwnerKind    string             `json:"ownerKind,omitempty"`
+	OwnerName    string             `json:"ownerName,omitempty"`
+	Deployment   *appsV1.Deployment `json:"-"`
+	RolloutState RolloutState       `json:"rolloutState,omitempty"`
+	Events       []EventSummary     `json:"events,omitempty"`
+	// 无法关联时的原因
+	Err error `json:"-"`
+}
+
+// 根据告警的namespace,pod,deployment和replicaset label,通过ownerReferences找到所属的Deployment,
+// 返回滚动更新状态和最近的事件.alerts为告警的label,顺序和返回结果一致
+func (c *Cluster) CorrelateAlerts(ctx context.Context, alerts []map[string]string, opts AlertWorkloadOptions) []AlertWorkload {
+	if opts.MaxEvents <= 0 {
+		opts.MaxEvents = defaultAlertEvents
+	}
+
+	result := make([]AlertWorkload, 0, len(alerts))
+	for _, labels := range alerts {
+		w := AlertWorkload{
+			Alert:     labels,
+			Namespace: firstLabel(labels, alertNamespaceLabels),
+			Pod:       firstLabel(labels, alertPodLabels),
+		}
+		w.Err = c.correlateAlert(ctx, &w, opts)
+		result = append(result, w)
+	}
+	return result
+}
+
+func (c *Cluster) correlateAlert(ctx context.Context, w *AlertWorkload, opts AlertWorkloadOptions) error {
+	if w.Namespace == "" {
+		return fmt.Errorf("alert has no namespace label")
+	}
+
+	var pod *coreV1.Pod
+	deploymentName := firstLabel(w.Alert, alertDeploymentLabels)
+	replicaSetName := firstLabel(w.Alert, alertReplicaSetLabels)
+	if deploymentName == "" && replicaSetName == "" {
+		if w.Pod == "" {
+			return fmt.Errorf("alert has no pod, deployment or replicaset label")
+		}
+
+		var err error
+		pod, err = c.client.CoreV1().Pods(w.Namespace).Get(ctx, w.Pod, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil {
+			return fmt.Errorf("pod %s has no controller", w.Pod)
+		}
+		if owner.Kind != "ReplicaSet" {
+			w.OwnerKind, w.OwnerName = owner.Kind, owner.Name
+			return nil
+		}
+		replicaSetName = owner.Name
+	}
+
+	if deploymentName == "" {
+		rs, err := c.client.AppsV1().ReplicaSets(w.Namespace).Get(ctx, replicaSetName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		owner := metav1.GetControllerOf(rs)
+		if owner == nil || owner.Kind != "Deployment" {
+			w.OwnerKind, w.OwnerName = "ReplicaSet", rs.Name
+			return nil
+		}
+		deploymentName = owner.Name
+	}
+
+	deploy, err := c.client.AppsV1().Deployments(w.Namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	w.OwnerKind, w.OwnerName = "Deployment", deploy.Name
+	w.Deployment = deploy
+	w.RolloutState = rolloutState(deploy)
+
+	events := newEvents(c.client, w.Namespace)
+	related, err := events.EventsFor(ctx, deploy)
+	if err != nil {
+		return err
+	}
+	if pod != nil {
+		podEvents, err := events.EventsFor(ctx, pod)
+		if err != nil {
+			return err
+		}
+		related = append(related, podEvents...)
+	}
+	w.Events = summarizeEvents(related, opts.MaxEvents)
+	return nil
+}
+
+func rolloutState(d *appsV1.Deployment) RolloutState {
+	if d.Spec.Paused {
+		return RolloutPaused
+	}
+	done, err := deploymentRolledOut(d)
+	switch {
+	case err != nil:
+		return RolloutFailed
+	case done:
+		return RolloutComplete
+	}
+	return RolloutProgressing
+}
+
+// summarizeEvents 按最后发生时间倒序,最多返回max条
+func summarizeEvents(events []coreV1.Event, max int) []EventSummary {
+	sort.Slice(events, func(i, j int) bool {
+		return eventTime(&events[i]).After(eventTime(&events[j]))
+	})
+	if len(events) > max {
+		events = events[:max]
+	}
+
+	summaries := make([]EventSummary, 0, len(events))
+	for i := range events {
+		e := &events[i]
+		summaries = append(summaries, EventSummary{
+			Type:          e.Type,
+			Reason:        e.Reason,
+			Object:        e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name,
+			Message:       e.Message,
+			Count:         e.Count,
+			LastTimestamp: eventTime(e),
+		})
+	}
+	return summaries
+}
+
+func firstLabel(labels map[string]string, names []string) string {
+	for _, name := range names {
+		if value := labels[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package v1
+
+import (
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestFirstLabel(t *testing.T) {
+	labels := map[string]string{"pod_name": "app-forum-5d9b6f84fb-g2pl6", "exported_namespace": "dev1-xiaomai-server"}
+	if got := firstLabel(labels, alertPodLabels); got != "app-forum-5d9b6f84fb-g2pl6" {
+		t.Fatalf("pod = %s", got)
+	}
+	if got := firstLabel(labels, alertNamespaceLabels); got != "dev1-xiaomai-server" {
+		t.Fatalf("namespace = %s", got)
+	}
+	if got := firstLabel(labels, alertDeploymentLabels); got != "" {
+		t.Fatalf("deployment = %s", got)
+	}
+
+	// kube-state-metrics的namespace和pod被改名为exported_*
+	labels = map[string]string{
+		"namespace": "monitoring", "pod": "kube-state-metrics-6c9f8b7d4-x2kq9",
+		"exported_namespace": "dev1-xiaomai-server", "exported_pod": "app-forum-5d9b6f84fb-g2pl6",
+	}
+	if got := firstLabel(labels, alertNamespaceLabels); got != "dev1-xiaomai-server" {
+		t.Fatalf("namespace = %s", got)
+	}
+	if got := firstLabel(labels, alertPodLabels); got != "app-forum-5d9b6f84fb-g2pl6" {
+		t.Fatalf("pod = %s", got)
+	}
+}
+
+func TestRolloutState(t *testing.T) {
+	replicas := int32(2)
+	d := &appsV1.Deployment{
+		Spec:   appsV1.DeploymentSpec{Replicas: &replicas},
+		Status: appsV1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+	}
+	if s := rolloutState(d); s != RolloutComplete {
+		t.Fatalf("state = %s", s)
+	}
+
+	d.Status.AvailableReplicas = 1
+	if s := rolloutState(d); s != RolloutProgressing {
+		t.Fatalf("state = %s", s)
+	}
+
+	d.Status.Conditions = []appsV1.DeploymentCondition{{Type: appsV1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded"}}
+	if s := rolloutState(d); s != RolloutFailed {
+		t.Fatalf("state = %s", s)
+	}
+
+	d.Spec.Paused = true
+	if s := rolloutState(d); s != RolloutPaused {
+		t.Fatalf("state = %s", s)
+	}
+}
+
+func TestSummarizeEvents(t *testing.T) {
+	now := time.Now()
+	events := []coreV1.Event{
+		{Reason: "Old", InvolvedObject: coreV1.ObjectReference{Kind: "Pod", Name: "a"}, LastTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+		{Reason: "New", InvolvedObject: coreV1.ObjectReference{Kind: "Deployment", Name: "b"}, LastTimestamp: metav1.NewTime(now)},
+		{Reason: "Middle", InvolvedObject: coreV1.ObjectReference{Kind: "Pod", Name: "a"}, LastTimestamp: metav1.NewTime(now.Add(-time.Minute))},
+	}
+
+	summaries := summarizeEvents(events, 2)
+	if len(summaries) != 2 || summaries[0].Reason != "New" || summaries[1].Reason != "Middle" {
+		t.Fatalf("summaries = %+v", summaries)
+	}
+	if summaries[0].Object != "Deployment/b" {
+		t.Fatalf("object = %s", summaries[0].Object)
+	}
+}
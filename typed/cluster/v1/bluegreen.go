@@ -0,0 +1,291 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"sort"
+	"time"
+)
+
+const (
+	ColorBlue  = "blue"
+	ColorGreen = "green"
+
+	// pod和Service selector中表示颜色的label
+	colorLabel = "color"
+	// 旧颜色在该时间之后可以被ScaleDownIdle缩容
+	scaleDownAfterAnnotation = "k8s-client/scale-down-after"
+)
+
+type BlueGreenGetter interface {
+	BlueGreen(namespace, name, service string) BlueGreenInterface
+}
+
+type BlueGreenInterface interface {
+	Active(ctx context.Context) (string, error)
+	Deploy(ctx context.Context, opts BlueGreenOptions) (string, error)
+	SwitchBack(ctx context.Context) (string, error)
+	ScaleDownIdle(ctx context.Context, force bool) error
+}
+
+type BlueGreenOptions struct {
+	// 新版本的pod模板,为空时在当前颜色的模板上修改Images中的镜像
+	Template *coreV1.PodTemplateSpec
+	Images   map[string]string
+	// 为空时使用当前颜色的副本数,首次发布默认为1
+	Replicas *int32
+	// 切换后旧颜色保留副本的时间,用于快速切回.
+	// Deploy只记录到期时间,不会自动缩容,调用方需要定期调用ScaleDownIdle(ctx, false)
+	KeepOldFor time.Duration
+}
+
+// blueGreen 维护<name>-blue和<name>-green两个deployment,通过Service的color selector切换流量
+type blueGreen struct {
+	client     *kubernetes.Clientset
+	ns         string
+	name       string
+	service    string
+	deployment *deployment
+}
+
+func newBlueGreen(c *kubernetes.Clientset, namespace, name, service string, recorder record.EventRecorder) *blueGreen {
+	return &blueGreen{
+		client:     c,
+		ns:         namespace,
+		name:       name,
+		service:    service,
+		deployment: newDeployment(c, namespace, recorder),
+	}
+}
+
+// 返回Service当前指向的颜色,还没有指向任何颜色时返回空字符串
+func (b *blueGreen) Active(ctx context.Context) (string, error) {
+	svc, err := b.client.CoreV1().Services(b.ns).Get(ctx, b.service, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return svc.Spec.Selector[colorLabel], nil
+}
+
+// 将新版本发布到空闲的颜色,就绪后切换Service,返回新的颜色.
+// 旧颜色保持原有副本数,超过KeepOldFor后由ScaleDownIdle缩容
+func (b *blueGreen) Deploy(ctx context.Context, opts BlueGreenOptions) (string, error) {
+	active, err := b.Active(ctx)
+	if err != nil {
+		return "", err
+	}
+	idle := otherColor(active)
+
+	var current *v1.Deployment
+	if active != "" {
+		current, err = b.deployment.Get(ctx, b.deploymentName(active), metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	template, err := b.template(current, opts)
+	if err != nil {
+		return "", err
+	}
+
+	replicas := int32(1)
+	if opts.Replicas != nil {
+		replicas = *opts.Replicas
+	} else if current != nil && current.Spec.Replicas != nil {
+		replicas = *current.Spec.Replicas
+	}
+
+	if err := b.apply(ctx, idle, template, replicas); err != nil {
+		return "", err
+	}
+	if err := newWorkloads(b.client, b.ns).WaitForRollout(ctx, KindDeployment, b.deploymentName(idle)); err != nil {
+		return "", fmt.Errorf("wait for %s rollout err: %v", b.deploymentName(idle), err)
+	}
+
+	if err := b.switchTo(ctx, idle); err != nil {
+		return "", err
+	}
+
+	if current != nil {
+		after := time.Now().Add(opts.KeepOldFor).UTC().Format(time.RFC3339)
+		if err := b.deployment.patchAnnotation(ctx, current.Name, scaleDownAfterAnnotation, after); err != nil {
+			return idle, err
+		}
+	}
+	return idle, nil
+}
+
+// 切回另一个颜色,要求另一个颜色的副本全部可用
+func (b *blueGreen) SwitchBack(ctx context.Context) (string, error) {
+	active, err := b.Active(ctx)
+	if err != nil {
+		return "", err
+	}
+	if active == "" {
+		return "", fmt.Errorf("service %s is not managed by blue/green", b.service)
+	}
+
+	previous := otherColor(active)
+	deploy, err := b.deployment.Get(ctx, b.deploymentName(previous), metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if ok, err := deploymentRolledOut(deploy); err != nil || !ok || deploy.Status.AvailableReplicas == 0 {
+		return "", fmt.Errorf("%s is not available, cannot switch back", deploy.Name)
+	}
+
+	if err := b.switchTo(ctx, previous); err != nil {
+		return "", err
+	}
+	return previous, b.deployment.removeAnnotation(ctx, deploy.Name, scaleDownAfterAnnotation)
+}
+
+// 将空闲颜色缩容到0,force为false时只缩容超过保留时间的颜色
+func (b *blueGreen) ScaleDownIdle(ctx context.Context, force bool) error {
+	active, err := b.Active(ctx)
+	if err != nil {
+		return err
+	}
+	if active == "" {
+		return nil
+	}
+
+	idle := b.deploymentName(otherColor(active))
+	deploy, err := b.deployment.Get(ctx, idle, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if !force {
+		after, err := time.Parse(time.RFC3339, deploy.Annotations[scaleDownAfterAnnotation])
+		if err != nil || time.Now().Before(after) {
+			return nil
+		}
+	}
+
+	if _, err := b.deployment.scale(ctx, idle, 0); err != nil {
+		return err
+	}
+	return b.deployment.removeAnnotation(ctx, idle, scaleDownAfterAnnotation)
+}
+
+// apply 创建或更新指定颜色的deployment
+func (b *blueGreen) apply(ctx context.Context, color string, template *coreV1.PodTemplateSpec, replicas int32) error {
+	name := b.deploymentName(color)
+	template = template.DeepCopy()
+	template.Labels = copyLabels(template.Labels)
+	template.Labels[colorLabel] = color
+
+	existing, err := b.deployment.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = b.client.AppsV1().Deployments(b.ns).Create(ctx, &v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: b.ns,
+				Labels:    copyLabels(template.Labels),
+			},
+			Spec: v1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: copyLabels(template.Labels)},
+				Template: *template,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	// selector不可修改,pod的label需要包含原有的selector
+	for k, v := range existing.Spec.Selector.MatchLabels {
+		template.Labels[k] = v
+	}
+	existing.Spec.Template = *template
+	existing.Spec.Replicas = &replicas
+	delete(existing.Annotations, scaleDownAfterAnnotation)
+	_, err = b.deployment.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// switchTo 修改Service的color selector,Update带有resourceVersion,并发修改时返回冲突
+func (b *blueGreen) switchTo(ctx context.Context, color string) error {
+	svc, err := b.client.CoreV1().Services(b.ns).Get(ctx, b.service, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// 切换前确认新颜色的pod能被Service的其他selector选中,否则切换后Service没有endpoint
+	deploy, err := b.deployment.Get(ctx, b.deploymentName(color), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if missing := missingSelectorLabels(svc.Spec.Selector, deploy.Spec.Template.Labels); len(missing) > 0 {
+		return fmt.Errorf("pods of %s do not match selector %v of service %s", deploy.Name, missing, b.service)
+	}
+
+	if svc.Spec.Selector == nil {
+		svc.Spec.Selector = make(map[string]string)
+	}
+	previous := svc.Spec.Selector[colorLabel]
+	svc.Spec.Selector[colorLabel] = color
+	updated, err := b.client.CoreV1().Services(b.ns).Update(ctx, svc, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	b.deployment.event(updated, coreV1.EventTypeNormal, "BlueGreenSwitched", "Switched service %s from %q to %q", b.service, previous, color)
+	return nil
+}
+
+// missingSelectorLabels 返回selector中除颜色外pod label不满足的key=value
+func missingSelectorLabels(selector, podLabels map[string]string) []string {
+	var missing []string
+	for k, v := range selector {
+		if k == colorLabel {
+			continue
+		}
+		if podLabels[k] != v {
+			missing = append(missing, k+"="+v)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func (b *blueGreen) template(current *v1.Deployment, opts BlueGreenOptions) (*coreV1.PodTemplateSpec, error) {
+	if opts.Template != nil {
+		return opts.Template.DeepCopy(), nil
+	}
+	if current == nil {
+		return nil, fmt.Errorf("template is required for the first blue/green deployment of %s", b.name)
+	}
+
+	template := current.Spec.Template.DeepCopy()
+	delete(template.Labels, colorLabel)
+	if err := setTemplateImages(template, opts.Images); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (b *blueGreen) deploymentName(color string) string {
+	return b.name + "-" + color
+}
+
+// otherColor 返回另一个颜色,没有颜色时从blue开始
+func otherColor(color string) string {
+	if color == ColorBlue {
+		return ColorGreen
+	}
+	return ColorBlue
+}
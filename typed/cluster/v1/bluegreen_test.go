@@ -0,0 +1,66 @@
+package v1
+
+import (
+	v1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestOtherColor(t *testing.T) {
+	cases := map[string]string{
+		"":         ColorBlue,
+		ColorBlue:  ColorGreen,
+		ColorGreen: ColorBlue,
+	}
+	for color, want := range cases {
+		if got := otherColor(color); got != want {
+			t.Fatalf("otherColor(%q) = %q, want %q", color, got, want)
+		}
+	}
+}
+
+func TestBlueGreen_Template(t *testing.T) {
+	b := &blueGreen{name: "app-forum"}
+	if _, err := b.template(nil, BlueGreenOptions{}); err == nil {
+		t.Fatal("expected error for the first deployment without template")
+	}
+
+	current := &v1.Deployment{
+		Spec: v1.DeploymentSpec{
+			Template: coreV1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "app-forum", colorLabel: ColorBlue}},
+				Spec:       coreV1.PodSpec{Containers: []coreV1.Container{{Name: "app-forum", Image: "app-forum:v1"}}},
+			},
+		},
+	}
+
+	template, err := b.template(current, BlueGreenOptions{Images: map[string]string{"app-forum": "app-forum:v2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.Spec.Containers[0].Image != "app-forum:v2" {
+		t.Fatalf("image = %s", template.Spec.Containers[0].Image)
+	}
+	if _, ok := template.Labels[colorLabel]; ok {
+		t.Fatal("color label should be removed from template")
+	}
+	if current.Spec.Template.Spec.Containers[0].Image != "app-forum:v1" {
+		t.Fatal("current deployment should not be modified")
+	}
+
+	if _, err := b.template(current, BlueGreenOptions{Images: map[string]string{"unknown": "x"}}); err == nil {
+		t.Fatal("expected error for unknown container")
+	}
+}
+
+func TestMissingSelectorLabels(t *testing.T) {
+	selector := map[string]string{"app": "app-forum", "tier": "web", colorLabel: ColorBlue}
+	if missing := missingSelectorLabels(selector, map[string]string{"app": "app-forum", "tier": "web", colorLabel: ColorGreen}); len(missing) != 0 {
+		t.Fatalf("missing = %v", missing)
+	}
+	missing := missingSelectorLabels(selector, map[string]string{"app": "app-forum-v2", colorLabel: ColorGreen})
+	if len(missing) != 2 || missing[0] != "app=app-forum" || missing[1] != "tier=web" {
+		t.Fatalf("missing = %v", missing)
+	}
+}
@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/flowcontrol"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultBulkConcurrency = 5
+
+// BulkOptions 批量操作的范围和并发控制
+type BulkOptions struct {
+	Namespaces    []string
+	LabelSelector string
+	// 同时进行的操作数,默认5
+	Concurrency int
+	// 每秒最多发起的操作数,为0时不限制
+	QPS float32
+}
+
+// BulkResult 单个对象的操作结果,Name为空表示列出namespace下的对象失败
+type BulkResult struct {
+	Namespace string
+	Name      string
+	Err       error
+	Duration  time.Duration
+}
+
+// BulkSummary 批量操作的结果汇总,按namespace和名称排序
+type BulkSummary struct {
+	Results []BulkResult
+}
+
+func (s *BulkSummary) Succeeded() []BulkResult {
+	var results []BulkResult
+	for _, r := range s.Results {
+		if r.Err == nil {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+func (s *BulkSummary) Failed() []BulkResult {
+	var results []BulkResult
+	for _, r := range s.Results {
+		if r.Err != nil {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// 所有失败的操作合并为一个错误,全部成功时返回nil
+func (s *BulkSummary) Err() error {
+	var errs []error
+	for _, r := range s.Failed() {
+		errs = append(errs, fmt.Errorf("%s/%s: %v", r.Namespace, r.Name, r.Err))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// 批量重启deployment
+func (c *Cluster) ReDeployAll(ctx context.Context, opts BulkOptions) *BulkSummary {
+	return c.forEachDeployment(ctx, opts, nil, func(ctx context.Context, d *deployment, deploy *appsV1.Deployment) error {
+		return d.ReDeploy(ctx, deploy.Name)
+	})
+}
+
+// 批量修改deployment的副本数
+func (c *Cluster) ScaleAll(ctx context.Context, opts BulkOptions, replicas int32, policy HPAPolicy) *BulkSummary {
+	return c.forEachDeployment(ctx, opts, nil, func(ctx context.Context, d *deployment, deploy *appsV1.Deployment) error {
+		_, err := d.Scale(ctx, deploy.Name, replicas, policy)
+		return err
+	})
+}
+
+// 批量修改deployment的镜像,images的key为容器名.
+// 每个deployment只修改它包含的容器,不包含任何容器的deployment被跳过,不出现在结果中
+func (c *Cluster) SetImageAll(ctx context.Context, opts BulkOptions, images map[string]string, setOpts SetImageOptions) *BulkSummary {
+	selected := func(deploy *appsV1.Deployment) bool {
+		return len(containerImages(&deploy.Spec.Template.Spec, images)) > 0
+	}
+	return c.forEachDeployment(ctx, opts, selected, func(ctx context.Context, d *deployment, deploy *appsV1.Deployment) error {
+		return newWorkloads(d.client, d.ns).SetImage(ctx, KindDeployment, deploy.Name, containerImages(&deploy.Spec.Template.Spec, images), setOpts)
+	})
+}
+
+// containerImages 返回images中podSpec包含的容器(包括init容器)
+func containerImages(podSpec *coreV1.PodSpec, images map[string]string) map[string]string {
+	result := make(map[string]string)
+	for _, containers := range [][]coreV1.Container{podSpec.Containers, podSpec.InitContainers} {
+		for _, container := range containers {
+			if image, ok := images[container.Name]; ok {
+				result[container.Name] = image
+			}
+		}
+	}
+	return result
+}
+
+// forEachDeployment 在selected为true的deployment上并发执行fn,selected为nil时选中全部,单个失败不影响其他对象
+func (c *Cluster) forEachDeployment(ctx context.Context, opts BulkOptions, selected func(deploy *appsV1.Deployment) bool, fn func(ctx context.Context, d *deployment, deploy *appsV1.Deployment) error) *BulkSummary {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+	var limiter flowcontrol.RateLimiter
+	if opts.QPS > 0 {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(opts.QPS, 1)
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		summary BulkSummary
+	)
+	record := func(r BulkResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		summary.Results = append(summary.Results, r)
+	}
+
+	sem := make(chan struct{}, concurrency)
+	for _, ns := range opts.Namespaces {
+		d := newDeployment(c.client, ns, c.recorder)
+		list, err := d.List(ctx, metav1.ListOptions{LabelSelector: opts.LabelSelector})
+		if err != nil {
+			record(BulkResult{Namespace: ns, Err: err})
+			continue
+		}
+
+		for i := range list.Items {
+			item := &list.Items[i]
+			name := item.Name
+			if selected != nil && !selected(item) {
+				continue
+			}
+			if limiter != nil {
+				if err := limiter.Wait(ctx); err != nil {
+					record(BulkResult{Namespace: ns, Name: name, Err: err})
+					continue
+				}
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				record(BulkResult{Namespace: ns, Name: name, Err: ctx.Err()})
+				continue
+			}
+
+			wg.Add(1)
+			go func(d *deployment, deploy *appsV1.Deployment) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				start := time.Now()
+				err := fn(ctx, d, deploy)
+				record(BulkResult{Namespace: d.ns, Name: deploy.Name, Err: err, Duration: time.Since(start)})
+			}(d, item)
+		}
+	}
+	wg.Wait()
+
+	sort.Slice(summary.Results, func(i, j int) bool {
+		if summary.Results[i].Namespace != summary.Results[j].Namespace {
+			return summary.Results[i].Namespace < summary.Results[j].Namespace
+		}
+		return summary.Results[i].Name < summary.Results[j].Name
+	})
+	return &summary
+}
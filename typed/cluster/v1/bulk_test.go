@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"testing"
+)
+
+func TestBulkSummary(t *testing.T) {
+	summary := &BulkSummary{Results: []BulkResult{
+		{Namespace: "dev1-xiaomai-server", Name: "dev1-app-forum-latest"},
+		{Namespace: "dev1-xiaomai-server", Name: "dev1-app-market-latest", Err: fmt.Errorf("conflict")},
+	}}
+
+	if len(summary.Succeeded()) != 1 || len(summary.Failed()) != 1 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if err := summary.Err(); err == nil || err.Error() != "dev1-xiaomai-server/dev1-app-market-latest: conflict" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestContainerImages(t *testing.T) {
+	images := map[string]string{"app-forum": "app-forum:v2", "filebeat": "filebeat:7.9"}
+	deployments := map[string]coreV1.PodSpec{
+		"dev1-app-forum-latest": {
+			Containers:     []coreV1.Container{{Name: "app-forum"}, {Name: "filebeat"}},
+			InitContainers: []coreV1.Container{{Name: "migrate"}},
+		},
+		"dev1-app-market-latest": {Containers: []coreV1.Container{{Name: "app-market"}, {Name: "filebeat"}}},
+		"dev1-redis":             {Containers: []coreV1.Container{{Name: "redis"}}},
+	}
+	want := map[string]string{
+		"dev1-app-forum-latest":  "app-forum=app-forum:v2 filebeat=filebeat:7.9",
+		"dev1-app-market-latest": "filebeat=filebeat:7.9",
+		// 没有匹配的容器,SetImageAll会跳过
+		"dev1-redis": "",
+	}
+
+	for name, spec := range deployments {
+		spec := spec
+		if got := formatImages(containerImages(&spec, images)); got != want[name] {
+			t.Fatalf("%s: images = %q, want %q", name, got, want[name])
+		}
+	}
+}
+
+func TestCluster_ReDeployAll(t *testing.T) {
+	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := NewForConfig(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	summary := client.ReDeployAll(ctx, BulkOptions{
+		Namespaces:    []string{"dev1-xiaomai-server"},
+		LabelSelector: "tier=backend",
+		Concurrency:   3,
+		QPS:           2,
+	})
+	for _, r := range summary.Results {
+		t.Logf("%s/%s %v %s", r.Namespace, r.Name, r.Err, r.Duration)
+	}
+	if err := summary.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,306 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"time"
+)
+
+const (
+	canarySuffix = "-canary"
+	// canary的pod额外带上该label,用于区分primary的pod
+	canaryTrackLabel = "track"
+	canaryTrackValue = "canary"
+)
+
+var defaultCanarySteps = []int32{10, 50, 100}
+
+// HealthGate 在每个步骤之后检查canary是否健康,返回错误时终止发布
+type HealthGate func(ctx context.Context, pods PodsInterface, canary *v1.Deployment) error
+
+// QueryFunc 执行PromQL查询并返回标量结果
+type QueryFunc func(ctx context.Context, query string) (float64, error)
+
+type CanaryOptions struct {
+	// 新版本的pod模板,为空时在primary的模板上修改Images中的镜像
+	Template *coreV1.PodTemplateSpec
+	Images   map[string]string
+	// canary副本数占总副本数的百分比,默认10,50,100
+	Steps []int32
+	// 每个步骤之后观察的时间
+	Interval time.Duration
+	Gates    []HealthGate
+}
+
+// CanaryResult canary发布的结果
+type CanaryResult struct {
+	Promoted bool
+	// 最后完成的步骤百分比
+	Step int32
+	// 终止发布的原因
+	AbortReason error
+}
+
+// 所有canary的pod都处于Ready
+func PodReadinessGate() HealthGate {
+	return func(ctx context.Context, pods PodsInterface, canary *v1.Deployment) error {
+		list, err := canaryPods(ctx, pods, canary)
+		if err != nil {
+			return err
+		}
+		for i := range list {
+			ready, err := PodReady(&list[i])
+			if err != nil {
+				return err
+			}
+			if !ready {
+				return fmt.Errorf("pod %s is not ready", list[i].Name)
+			}
+		}
+		return nil
+	}
+}
+
+// canary的pod重启次数之和不超过max
+func RestartCountGate(max int32) HealthGate {
+	return func(ctx context.Context, pods PodsInterface, canary *v1.Deployment) error {
+		list, err := canaryPods(ctx, pods, canary)
+		if err != nil {
+			return err
+		}
+
+		var restarts int32
+		for _, pod := range list {
+			for _, status := range pod.Status.ContainerStatuses {
+				restarts += status.RestartCount
+			}
+		}
+		if restarts > max {
+			return fmt.Errorf("canary pods restarted %d times, max %d", restarts, max)
+		}
+		return nil
+	}
+}
+
+// PromQL查询结果不超过max,例如canary的5xx比例
+func PromQLGate(query string, eval QueryFunc, max float64) HealthGate {
+	return func(ctx context.Context, pods PodsInterface, canary *v1.Deployment) error {
+		value, err := eval(ctx, query)
+		if err != nil {
+			return fmt.Errorf("query %q err: %v", query, err)
+		}
+		if value > max {
+			return fmt.Errorf("query %q returned %v, max %v", query, value, max)
+		}
+		return nil
+	}
+}
+
+// 创建<name>-canary的deployment,按Steps逐步把副本从primary转移到canary,
+// 每一步之后检查Gates.全部通过后用新模板更新primary并删除canary,
+// 检查失败时恢复primary的副本数并删除canary.
+// canary的pod保留primary的全部label,因此会被同一个Service选中.
+// primary被HPA管理时,HPA可能会干扰副本数的调整
+func (d *deployment) Canary(ctx context.Context, name string, opts CanaryOptions) (*CanaryResult, error) {
+	primary, err := d.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	total := int32(1)
+	if primary.Spec.Replicas != nil {
+		total = *primary.Spec.Replicas
+	}
+
+	template, err := canaryTemplate(primary, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	canary, err := d.client.AppsV1().Deployments(d.ns).Create(ctx, newCanaryDeployment(primary, template), metav1.CreateOptions{})
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("canary of deployment %s is already in progress", name)
+		}
+		return nil, err
+	}
+	canaryName := canary.Name
+	d.event(primary, coreV1.EventTypeNormal, "CanaryStarted", "Started canary %s", canaryName)
+
+	steps := opts.Steps
+	if len(steps) == 0 {
+		steps = defaultCanarySteps
+	}
+
+	result := &CanaryResult{}
+	rollout := newWorkloads(d.client, d.ns)
+	pods := newPods(d.client, d.ns, nil)
+	for _, step := range steps {
+		canaryReplicas := canaryReplicasForStep(total, step)
+		err := d.canaryStep(ctx, name, canaryName, canaryReplicas, total-canaryReplicas, rollout)
+		if err == nil {
+			err = sleepContext(ctx, opts.Interval)
+		}
+		if err == nil {
+			canary, err = d.Get(ctx, canaryName, metav1.GetOptions{})
+		}
+		if err == nil {
+			for _, gate := range opts.Gates {
+				if err = gate(ctx, pods, canary); err != nil {
+					break
+				}
+			}
+		}
+
+		if err != nil {
+			result.AbortReason = err
+			klog.Warningf("abort canary of deployment %s/%s at step %d%%: %v", d.ns, name, step, err)
+			d.event(primary, coreV1.EventTypeWarning, "CanaryAborted", "Aborted canary at step %d%%: %v", step, err)
+			return result, d.abortCanary(context.Background(), name, canaryName, total, err)
+		}
+		result.Step = step
+	}
+
+	if err := d.promoteCanary(ctx, name, template, total, rollout); err != nil {
+		// primary已经缩容到最后一步的副本数,需要恢复并删除canary,否则之后的Canary都会失败
+		err = fmt.Errorf("promote err: %v", err)
+		result.AbortReason = err
+		klog.Warningf("abort canary of deployment %s/%s: %v", d.ns, name, err)
+		d.event(primary, coreV1.EventTypeWarning, "CanaryAborted", "Aborted canary: %v", err)
+		return result, d.abortCanary(context.Background(), name, canaryName, total, err)
+	}
+	result.Promoted = true
+	d.event(primary, coreV1.EventTypeNormal, "CanaryPromoted", "Promoted canary %s", canaryName)
+
+	return result, d.Delete(ctx, canaryName, metav1.DeleteOptions{})
+}
+
+// canaryStep 先扩容canary并等待就绪,再缩容primary
+func (d *deployment) canaryStep(ctx context.Context, primary, canary string, canaryReplicas, primaryReplicas int32, rollout *workloads) error {
+	if _, err := d.scale(ctx, canary, canaryReplicas); err != nil {
+		return err
+	}
+	if err := rollout.WaitForRollout(ctx, KindDeployment, canary); err != nil {
+		return fmt.Errorf("wait for canary rollout err: %v", err)
+	}
+
+	_, err := d.scale(ctx, primary, primaryReplicas)
+	return err
+}
+
+// promoteCanary 用新模板更新primary并恢复副本数
+func (d *deployment) promoteCanary(ctx context.Context, name string, template *coreV1.PodTemplateSpec, replicas int32, rollout *workloads) error {
+	primary, err := d.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	primary.Spec.Template = *template
+	primary.Spec.Replicas = &replicas
+	if _, err := d.Update(ctx, primary, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	return rollout.WaitForRollout(ctx, KindDeployment, name)
+}
+
+// abortCanary 恢复primary的副本数并删除canary,ctx可能已经结束,因此使用新的ctx
+func (d *deployment) abortCanary(ctx context.Context, name, canary string, replicas int32, reason error) error {
+	if _, err := d.scale(ctx, name, replicas); err != nil {
+		return fmt.Errorf("canary aborted: %v, restore primary replicas err: %v", reason, err)
+	}
+	if err := d.Delete(ctx, canary, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("canary aborted: %v, delete canary err: %v", reason, err)
+	}
+	return fmt.Errorf("canary aborted: %v", reason)
+}
+
+func canaryTemplate(primary *v1.Deployment, opts CanaryOptions) (*coreV1.PodTemplateSpec, error) {
+	if opts.Template != nil {
+		return opts.Template.DeepCopy(), nil
+	}
+
+	template := primary.Spec.Template.DeepCopy()
+	if err := setTemplateImages(template, opts.Images); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func newCanaryDeployment(primary *v1.Deployment, template *coreV1.PodTemplateSpec) *v1.Deployment {
+	canary := &v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      primary.Name + canarySuffix,
+			Namespace: primary.Namespace,
+			Labels:    copyLabels(primary.Labels),
+		},
+		Spec: *primary.Spec.DeepCopy(),
+	}
+	canary.Labels[canaryTrackLabel] = canaryTrackValue
+
+	var zero int32
+	canary.Spec.Replicas = &zero
+	canary.Spec.Paused = false
+	canary.Spec.Template = *template.DeepCopy()
+	canary.Spec.Template.Labels = copyLabels(template.Labels)
+	canary.Spec.Template.Labels[canaryTrackLabel] = canaryTrackValue
+	if canary.Spec.Selector == nil {
+		canary.Spec.Selector = &metav1.LabelSelector{}
+	}
+	canary.Spec.Selector.MatchLabels = copyLabels(canary.Spec.Selector.MatchLabels)
+	canary.Spec.Selector.MatchLabels[canaryTrackLabel] = canaryTrackValue
+
+	return canary
+}
+
+// canaryReplicasForStep 按百分比计算canary的副本数,至少为1
+func canaryReplicasForStep(total, step int32) int32 {
+	replicas := (total*step + 99) / 100
+	if replicas < 1 {
+		replicas = 1
+	}
+	if replicas > total && total > 0 {
+		replicas = total
+	}
+	return replicas
+}
+
+func canaryPods(ctx context.Context, pods PodsInterface, canary *v1.Deployment) ([]coreV1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(canary.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	copied := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		copied[k] = v
+	}
+	return copied
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,61 @@
+package v1
+
+import (
+	v1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestCanaryReplicasForStep(t *testing.T) {
+	cases := []struct {
+		total, step, want int32
+	}{
+		{10, 10, 1},
+		{10, 50, 5},
+		{10, 100, 10},
+		{3, 10, 1},
+		{3, 50, 2},
+		{1, 10, 1},
+	}
+	for _, c := range cases {
+		if got := canaryReplicasForStep(c.total, c.step); got != c.want {
+			t.Fatalf("canaryReplicasForStep(%d, %d) = %d, want %d", c.total, c.step, got, c.want)
+		}
+	}
+}
+
+func TestNewCanaryDeployment(t *testing.T) {
+	primary := &v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-forum", Namespace: "dev1-xiaomai-server", Labels: map[string]string{"app": "app-forum"}},
+		Spec: v1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app-forum"}},
+			Template: coreV1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "app-forum"}},
+				Spec:       coreV1.PodSpec{Containers: []coreV1.Container{{Name: "app-forum", Image: "app-forum:v1"}}},
+			},
+		},
+	}
+
+	template, err := canaryTemplate(primary, CanaryOptions{Images: map[string]string{"app-forum": "app-forum:v2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	canary := newCanaryDeployment(primary, template)
+
+	if canary.Name != "app-forum-canary" || *canary.Spec.Replicas != 0 {
+		t.Fatalf("unexpected canary: %s replicas %d", canary.Name, *canary.Spec.Replicas)
+	}
+	if canary.Spec.Template.Spec.Containers[0].Image != "app-forum:v2" {
+		t.Fatalf("canary image not updated: %s", canary.Spec.Template.Spec.Containers[0].Image)
+	}
+	if canary.Spec.Template.Labels["app"] != "app-forum" || canary.Spec.Selector.MatchLabels[canaryTrackLabel] != canaryTrackValue {
+		t.Fatalf("unexpected canary labels: %v %v", canary.Spec.Template.Labels, canary.Spec.Selector.MatchLabels)
+	}
+	if _, ok := primary.Spec.Selector.MatchLabels[canaryTrackLabel]; ok {
+		t.Fatal("primary selector must not be modified")
+	}
+	if primary.Spec.Template.Spec.Containers[0].Image != "app-forum:v1" {
+		t.Fatal("primary template must not be modified")
+	}
+}
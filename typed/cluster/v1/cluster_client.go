@@ -1,24 +1,48 @@
 package v1
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedCoreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/record"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
 type ClusterInterface interface {
 	DeploymentGetter
 	PodsGetter
+	EventsGetter
+	WorkloadsGetter
+	BlueGreenGetter
+	NamespacesGetter
+
+	RecordEvents(component string) func()
+	EventRecorder() record.EventRecorder
+
+	ReDeployAll(ctx context.Context, opts BulkOptions) *BulkSummary
+	ScaleAll(ctx context.Context, opts BulkOptions, replicas int32, policy HPAPolicy) *BulkSummary
+	SetImageAll(ctx context.Context, opts BulkOptions, images map[string]string, setOpts SetImageOptions) *BulkSummary
+	CloneNamespace(ctx context.Context, src, dst string, opts CloneOptions) (*CloneReport, error)
+	Janitor(opts JanitorOptions) *Janitor
+	LeaderElector(name, namespace string, onStart func(ctx context.Context), onStop func()) (*LeaderElector, error)
+	RunWithLeaderElection(ctx context.Context, name, namespace string, onStart func(ctx context.Context), onStop func()) error
+	CorrelateAlerts(ctx context.Context, alerts []map[string]string, opts AlertWorkloadOptions) []AlertWorkload
 }
 
 type Cluster struct {
 	client     *kubernetes.Clientset
 	restConfig *rest.Config
+	recorder   *eventRecorder
 }
 
 func NewForConfig(c *rest.Config) (*Cluster, error) {
@@ -32,6 +56,7 @@ func NewForConfig(c *rest.Config) (*Cluster, error) {
 	return &Cluster{
 		client:     client,
 		restConfig: c,
+		recorder:   &eventRecorder{},
 	}, nil
 }
 
@@ -40,7 +65,7 @@ func (c *Cluster) Deployment(namespace string) DeploymentInterface {
 		return nil
 	}
 
-	return newDeployment(c.client, namespace)
+	return newDeployment(c.client, namespace, c.recorder)
 }
 
 func (c *Cluster) Pods(namespace string) PodsInterface {
@@ -55,6 +80,114 @@ func (c *Cluster) HorizontalPodAutoScalers(namespace string) HorizontalPodAutoSc
 	return newHorizontalPodAutoScaler(c.client, namespace)
 }
 
+func (c *Cluster) Namespaces() NamespaceInterface {
+	return newNamespaces(c.client)
+}
+
+func (c *Cluster) Events(namespace string) EventsInterface {
+	return newEvents(c.client, namespace)
+}
+
+func (c *Cluster) Workloads(namespace string) WorkloadsInterface {
+	return newWorkloads(c.client, namespace)
+}
+
+// 通过Service的selector在<name>-blue和<name>-green之间切换流量
+func (c *Cluster) BlueGreen(namespace, name, service string) BlueGreenInterface {
+	return newBlueGreen(c.client, namespace, name, service, c.recorder)
+}
+
+// 开启后ReDeploy等操作会在对象上记录事件,可以通过kubectl describe查看.
+// 已经创建的client也会开始记录,返回的函数用于停止记录
+func (c *Cluster) RecordEvents(component string) func() {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedCoreV1.EventSinkImpl{Interface: c.client.CoreV1().Events("")})
+	c.recorder.start(broadcaster, broadcaster.NewRecorder(scheme.Scheme, coreV1.EventSource{Component: component}))
+
+	return func() {
+		c.recorder.stop(broadcaster)
+	}
+}
+
+// 返回Cluster共用的EventRecorder,未开启RecordEvents时丢弃事件
+func (c *Cluster) EventRecorder() record.EventRecorder {
+	return c.recorder
+}
+
+// eventRecorder 在多个client之间共用,RecordEvents开启之前和停止之后丢弃事件.
+// 记录事件时持有读锁,保证broadcaster关闭之后不会再写入
+type eventRecorder struct {
+	mu          sync.RWMutex
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+}
+
+func (r *eventRecorder) start(broadcaster record.EventBroadcaster, recorder record.EventRecorder) {
+	r.mu.Lock()
+	previous := r.broadcaster
+	r.broadcaster, r.recorder = broadcaster, recorder
+	r.mu.Unlock()
+
+	if previous != nil {
+		previous.Shutdown()
+	}
+}
+
+// stop 只在broadcaster仍然是当前使用的broadcaster时停止记录
+func (r *eventRecorder) stop(broadcaster record.EventBroadcaster) {
+	r.mu.Lock()
+	if r.broadcaster != broadcaster {
+		r.mu.Unlock()
+		return
+	}
+	r.broadcaster, r.recorder = nil, nil
+	r.mu.Unlock()
+
+	broadcaster.Shutdown()
+}
+
+func (r *eventRecorder) enabled() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.recorder != nil
+}
+
+func (r *eventRecorder) Event(object runtime.Object, eventType, reason, message string) {
+	if r == nil {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.recorder != nil {
+		r.recorder.Event(object, eventType, reason, message)
+	}
+}
+
+func (r *eventRecorder) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if r == nil {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.recorder != nil {
+		r.recorder.Eventf(object, eventType, reason, messageFmt, args...)
+	}
+}
+
+func (r *eventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventType, reason, messageFmt string, args ...interface{}) {
+	if r == nil {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.recorder != nil {
+		r.recorder.AnnotatedEventf(object, annotations, eventType, reason, messageFmt, args...)
+	}
+}
+
 func KubeConfigGetter() (*clientcmdapi.Config, error) {
 	var configPath string
 
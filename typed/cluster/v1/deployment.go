@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	v1 "k8s.io/api/apps/v1"
+	autoscalingV1 "k8s.io/api/autoscaling/v1"
 	coreV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"regexp"
 	"strconv"
@@ -27,17 +30,29 @@ type DeploymentInterface interface {
 	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
 	ListWatch(ctx context.Context)
 	ReDeploy(ctx context.Context, name string) error
+	Diagnose(ctx context.Context, name string) (*DiagnosisReport, error)
+	History(ctx context.Context, name string) ([]DeploymentRevision, error)
+	Rollback(ctx context.Context, name string, revision int64) (*v1.Deployment, error)
+	GetScale(ctx context.Context, name string) (*autoscalingV1.Scale, error)
+	Scale(ctx context.Context, name string, replicas int32, policy HPAPolicy) (*autoscalingV1.Scale, error)
+	Pause(ctx context.Context, name string) error
+	Resume(ctx context.Context, name string) error
+	ScaleToZero(ctx context.Context, name string) error
+	RestoreScale(ctx context.Context, name string) error
+	Canary(ctx context.Context, name string, opts CanaryOptions) (*CanaryResult, error)
 }
 
 type deployment struct {
-	client *kubernetes.Clientset
-	ns     string
+	client   *kubernetes.Clientset
+	ns       string
+	recorder record.EventRecorder
 }
 
-func newDeployment(c *kubernetes.Clientset, namespace string) *deployment {
+func newDeployment(c *kubernetes.Clientset, namespace string, recorder record.EventRecorder) *deployment {
 	return &deployment{
-		client: c,
-		ns:     namespace,
+		client:   c,
+		ns:       namespace,
+		recorder: recorder,
 	}
 }
 
@@ -192,8 +207,28 @@ func (d *deployment) ReDeploy(ctx context.Context, name string) error {
 	}
 
 	// 对已经修改完成的deployment进行更新
-	_, err = d.Update(ctx, deployment, metav1.UpdateOptions{})
-	return err
+	updated, err := d.Update(ctx, deployment, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	d.event(updated, coreV1.EventTypeNormal, "ReDeploy", "Restarted deployment %s", name)
+	return nil
+}
+
+// 开启事件记录时在对象上记录事件
+func (d *deployment) event(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if !d.recording() {
+		return
+	}
+	d.recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
+
+func (d *deployment) recording() bool {
+	if r, ok := d.recorder.(*eventRecorder); ok {
+		return r.enabled()
+	}
+	return d.recorder != nil
 }
 
 func regexReDeployDomain(domain string) (int, bool) {
@@ -0,0 +1,379 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	v1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+	// CrashLoopBackOff时获取的日志行数
+	diagnoseLogLines = 20
+)
+
+type CauseKind string
+
+const (
+	CauseImagePullBackOff CauseKind = "ImagePullBackOff"
+	CauseOOMKilled        CauseKind = "OOMKilled"
+	CauseCrashLoopBackOff CauseKind = "CrashLoopBackOff"
+	CauseUnschedulable    CauseKind = "Unschedulable"
+	CauseProbeFailed      CauseKind = "ProbeFailed"
+)
+
+// DiagnosisReport deployment的健康诊断报告
+type DiagnosisReport struct {
+	Namespace         string              `json:"namespace"`
+	Deployment        string              `json:"deployment"`
+	Replicas          int32               `json:"replicas"`
+	UpdatedReplicas   int32               `json:"updatedReplicas"`
+	ReadyReplicas     int32               `json:"readyReplicas"`
+	AvailableReplicas int32               `json:"availableReplicas"`
+	Conditions        []string            `json:"conditions,omitempty"`
+	ReplicaSets       []ReplicaSetSummary `json:"replicaSets,omitempty"`
+	Pods              []PodSummary        `json:"pods,omitempty"`
+	Causes            []Cause             `json:"causes,omitempty"`
+	Events            []EventSummary      `json:"events,omitempty"`
+}
+
+type ReplicaSetSummary struct {
+	Name          string `json:"name"`
+	Revision      string `json:"revision"`
+	Replicas      int32  `json:"replicas"`
+	ReadyReplicas int32  `json:"readyReplicas"`
+}
+
+type PodSummary struct {
+	Name       string             `json:"name"`
+	ReplicaSet string             `json:"replicaSet"`
+	Phase      coreV1.PodPhase    `json:"phase"`
+	Node       string             `json:"node,omitempty"`
+	Containers []ContainerSummary `json:"containers,omitempty"`
+}
+
+type ContainerSummary struct {
+	Name         string `json:"name"`
+	Image        string `json:"image"`
+	Ready        bool   `json:"ready"`
+	RestartCount int32  `json:"restartCount"`
+	State        string `json:"state"`
+}
+
+// Cause 归类后的异常原因
+type Cause struct {
+	Kind      CauseKind `json:"kind"`
+	Pod       string    `json:"pod"`
+	Container string    `json:"container,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	// CrashLoopBackOff时上一次运行的最后几行日志
+	Logs []string `json:"logs,omitempty"`
+}
+
+type EventSummary struct {
+	Type          string    `json:"type"`
+	Reason        string    `json:"reason"`
+	Object        string    `json:"object"`
+	Message       string    `json:"message"`
+	Count         int32     `json:"count"`
+	LastTimestamp time.Time `json:"lastTimestamp"`
+}
+
+// 诊断报告中没有发现异常原因并且副本全部可用
+func (r *DiagnosisReport) Healthy() bool {
+	return len(r.Causes) == 0 && r.AvailableReplicas >= r.Replicas
+}
+
+func (r *DiagnosisReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func (r *DiagnosisReport) Text() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Deployment: %s/%s\n", r.Namespace, r.Deployment)
+	fmt.Fprintf(&b, "Replicas: %d desired | %d updated | %d ready | %d available\n", r.Replicas, r.UpdatedReplicas, r.ReadyReplicas, r.AvailableReplicas)
+	for _, c := range r.Conditions {
+		fmt.Fprintf(&b, "Condition: %s\n", c)
+	}
+
+	if len(r.ReplicaSets) > 0 {
+		fmt.Fprintf(&b, "\nReplicaSets:\n")
+		for _, rs := range r.ReplicaSets {
+			fmt.Fprintf(&b, "  %s (revision %s) %d/%d ready\n", rs.Name, rs.Revision, rs.ReadyReplicas, rs.Replicas)
+		}
+	}
+
+	if len(r.Pods) > 0 {
+		fmt.Fprintf(&b, "\nPods:\n")
+		for _, pod := range r.Pods {
+			fmt.Fprintf(&b, "  %s %s %s\n", pod.Name, pod.Phase, pod.Node)
+			for _, c := range pod.Containers {
+				fmt.Fprintf(&b, "    %s ready=%t restarts=%d %s\n", c.Name, c.Ready, c.RestartCount, c.State)
+			}
+		}
+	}
+
+	if len(r.Causes) > 0 {
+		fmt.Fprintf(&b, "\nCauses:\n")
+		for _, c := range r.Causes {
+			object := c.Pod
+			if c.Container != "" {
+				object += "/" + c.Container
+			}
+			fmt.Fprintf(&b, "  [%s] %s: %s\n", c.Kind, object, c.Message)
+			for _, line := range c.Logs {
+				fmt.Fprintf(&b, "    | %s\n", line)
+			}
+		}
+	}
+
+	if len(r.Events) > 0 {
+		fmt.Fprintf(&b, "\nEvents:\n")
+		for _, e := range r.Events {
+			fmt.Fprintf(&b, "  %s %s %s (x%d): %s\n", e.Type, e.Reason, e.Object, e.Count, e.Message)
+		}
+	}
+
+	return b.String()
+}
+
+// 依次检查deployment,replicaset,pod,容器和事件,给出异常原因
+func (d *deployment) Diagnose(ctx context.Context, name string) (*DiagnosisReport, error) {
+	deploy, err := d.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DiagnosisReport{
+		Namespace:         d.ns,
+		Deployment:        name,
+		UpdatedReplicas:   deploy.Status.UpdatedReplicas,
+		ReadyReplicas:     deploy.Status.ReadyReplicas,
+		AvailableReplicas: deploy.Status.AvailableReplicas,
+	}
+	if deploy.Spec.Replicas != nil {
+		report.Replicas = *deploy.Spec.Replicas
+	}
+	for _, c := range deploy.Status.Conditions {
+		report.Conditions = append(report.Conditions, fmt.Sprintf("%s=%s %s", c.Type, c.Status, c.Message))
+	}
+
+	replicaSets, err := d.ownedReplicaSets(ctx, deploy)
+	if err != nil {
+		return nil, err
+	}
+
+	// 需要查看事件的对象
+	objects := map[types.UID]string{deploy.UID: "Deployment/" + deploy.Name}
+	owners := make(map[types.UID]string, len(replicaSets))
+	for _, rs := range replicaSets {
+		owners[rs.UID] = rs.Name
+		objects[rs.UID] = "ReplicaSet/" + rs.Name
+		summary := ReplicaSetSummary{
+			Name:          rs.Name,
+			Revision:      rs.Annotations[revisionAnnotation],
+			ReadyReplicas: rs.Status.ReadyReplicas,
+		}
+		if rs.Spec.Replicas != nil {
+			summary.Replicas = *rs.Spec.Replicas
+		}
+		report.ReplicaSets = append(report.ReplicaSets, summary)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	podList, err := d.client.CoreV1().Pods(d.ns).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range podList.Items {
+		owner := metav1.GetControllerOf(&pod)
+		if owner == nil || owners[owner.UID] == "" {
+			continue
+		}
+		objects[pod.UID] = "Pod/" + pod.Name
+		report.Pods = append(report.Pods, summarizePod(&pod, owners[owner.UID]))
+		report.Causes = append(report.Causes, d.podCauses(ctx, &pod)...)
+	}
+
+	// 按对象的uid查询事件,避免在共享的namespace中列出所有事件
+	var related []coreV1.Event
+	for uid := range objects {
+		events, err := d.client.CoreV1().Events(d.ns).List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("involvedObject.uid", string(uid)).String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		related = append(related, events.Items...)
+	}
+	sort.Slice(related, func(i, j int) bool {
+		return eventTime(&related[i]).After(eventTime(&related[j]))
+	})
+
+	probeFailed := make(map[string]bool)
+	for _, e := range related {
+		report.Events = append(report.Events, EventSummary{
+			Type:          e.Type,
+			Reason:        e.Reason,
+			Object:        objects[e.InvolvedObject.UID],
+			Message:       e.Message,
+			Count:         e.Count,
+			LastTimestamp: eventTime(&e),
+		})
+
+		// 探针失败只能从事件中获取,每个pod只保留最近的一条
+		if e.Reason == "Unhealthy" && e.InvolvedObject.Kind == "Pod" && !probeFailed[e.InvolvedObject.Name] {
+			probeFailed[e.InvolvedObject.Name] = true
+			report.Causes = append(report.Causes, Cause{
+				Kind:      CauseProbeFailed,
+				Pod:       e.InvolvedObject.Name,
+				Container: containerFromFieldPath(e.InvolvedObject.FieldPath),
+				Message:   e.Message,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// ownedReplicaSets 返回deployment拥有的replicaset
+func (d *deployment) ownedReplicaSets(ctx context.Context, deploy *v1.Deployment) ([]v1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := d.client.AppsV1().ReplicaSets(d.ns).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	var owned []v1.ReplicaSet
+	for _, rs := range list.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID == deploy.UID {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// podCauses 根据pod和容器的状态归类异常原因
+func (d *deployment) podCauses(ctx context.Context, pod *coreV1.Pod) []Cause {
+	var causes []Cause
+	for _, c := range pod.Status.Conditions {
+		if c.Type == coreV1.PodScheduled && c.Status == coreV1.ConditionFalse && c.Reason == coreV1.PodReasonUnschedulable {
+			causes = append(causes, Cause{Kind: CauseUnschedulable, Pod: pod.Name, Message: c.Message})
+		}
+	}
+
+	statuses := append(append([]coreV1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil {
+			switch waiting.Reason {
+			case "ImagePullBackOff", "ErrImagePull", "InvalidImageName":
+				causes = append(causes, Cause{
+					Kind:      CauseImagePullBackOff,
+					Pod:       pod.Name,
+					Container: status.Name,
+					Message:   fmt.Sprintf("%s: %s", status.Image, waiting.Message),
+				})
+			case "CrashLoopBackOff":
+				causes = append(causes, Cause{
+					Kind:      CauseCrashLoopBackOff,
+					Pod:       pod.Name,
+					Container: status.Name,
+					Message:   waiting.Message,
+					Logs:      d.previousLogs(ctx, pod.Name, status.Name),
+				})
+			}
+		}
+
+		if oomKilled(status.State.Terminated) || oomKilled(status.LastTerminationState.Terminated) {
+			causes = append(causes, Cause{
+				Kind:      CauseOOMKilled,
+				Pod:       pod.Name,
+				Container: status.Name,
+				Message:   fmt.Sprintf("container was OOMKilled, restarted %d times", status.RestartCount),
+			})
+		}
+	}
+
+	return causes
+}
+
+// previousLogs 获取容器上一次运行的最后几行日志
+func (d *deployment) previousLogs(ctx context.Context, pod, container string) []string {
+	tailLines := int64(diagnoseLogLines)
+	raw, err := d.client.CoreV1().Pods(d.ns).GetLogs(pod, &coreV1.PodLogOptions{
+		Container: container,
+		Previous:  true,
+		TailLines: &tailLines,
+	}).DoRaw(ctx)
+	if err != nil {
+		return []string{fmt.Sprintf("get logs err: %v", err)}
+	}
+
+	logs := strings.TrimRight(string(raw), "\n")
+	if logs == "" {
+		return nil
+	}
+	return strings.Split(logs, "\n")
+}
+
+func summarizePod(pod *coreV1.Pod, replicaSet string) PodSummary {
+	summary := PodSummary{
+		Name:       pod.Name,
+		ReplicaSet: replicaSet,
+		Phase:      pod.Status.Phase,
+		Node:       pod.Spec.NodeName,
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		summary.Containers = append(summary.Containers, ContainerSummary{
+			Name:         status.Name,
+			Image:        status.Image,
+			Ready:        status.Ready,
+			RestartCount: status.RestartCount,
+			State:        containerState(status.State),
+		})
+	}
+	return summary
+}
+
+func containerState(state coreV1.ContainerState) string {
+	switch {
+	case state.Running != nil:
+		return "Running"
+	case state.Waiting != nil:
+		return "Waiting: " + state.Waiting.Reason
+	case state.Terminated != nil:
+		return fmt.Sprintf("Terminated: %s (exit code %d)", state.Terminated.Reason, state.Terminated.ExitCode)
+	}
+	return "Unknown"
+}
+
+func oomKilled(state *coreV1.ContainerStateTerminated) bool {
+	return state != nil && state.Reason == "OOMKilled"
+}
+
+// containerFromFieldPath 从spec.containers{name}中解析容器名
+func containerFromFieldPath(fieldPath string) string {
+	start := strings.Index(fieldPath, "{")
+	end := strings.LastIndex(fieldPath, "}")
+	if start < 0 || end <= start {
+		return ""
+	}
+	return fieldPath[start+1 : end]
+}
@@ -0,0 +1,71 @@
+package v1
+
+import (
+	"context"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"strings"
+	"testing"
+)
+
+func TestDeployment_PodCauses(t *testing.T) {
+	pod := &coreV1.Pod{
+		Status: coreV1.PodStatus{
+			Conditions: []coreV1.PodCondition{{
+				Type:    coreV1.PodScheduled,
+				Status:  coreV1.ConditionFalse,
+				Reason:  coreV1.PodReasonUnschedulable,
+				Message: "0/3 nodes are available: 3 Insufficient memory.",
+			}},
+			ContainerStatuses: []coreV1.ContainerStatus{
+				{
+					Name:  "app",
+					Image: "registry.local/app:v2",
+					State: coreV1.ContainerState{Waiting: &coreV1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+				},
+				{
+					Name:                 "sidecar",
+					State:                coreV1.ContainerState{Running: &coreV1.ContainerStateRunning{}},
+					LastTerminationState: coreV1.ContainerState{Terminated: &coreV1.ContainerStateTerminated{Reason: "OOMKilled"}},
+				},
+			},
+		},
+	}
+	pod.Name = "app-7d9f8c-abcde"
+
+	d := &deployment{}
+	causes := d.podCauses(context.Background(), pod)
+	kinds := make([]string, 0, len(causes))
+	for _, c := range causes {
+		kinds = append(kinds, string(c.Kind))
+	}
+	if strings.Join(kinds, ",") != "Unschedulable,ImagePullBackOff,OOMKilled" {
+		t.Fatalf("unexpected causes: %v", kinds)
+	}
+
+	report := &DiagnosisReport{Namespace: "dev", Deployment: "app", Replicas: 1, Causes: causes}
+	if report.Healthy() {
+		t.Fatal("report should not be healthy")
+	}
+	if !strings.Contains(report.Text(), "[OOMKilled] app-7d9f8c-abcde/sidecar") {
+		t.Fatalf("unexpected text report:\n%s", report.Text())
+	}
+}
+
+func TestDeployment_Diagnose(t *testing.T) {
+	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := NewForConfig(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	report, err := client.Deployment("dev1-xiaomai-server").Diagnose(ctx, "dev1-app-forum-latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log(report.Text())
+}
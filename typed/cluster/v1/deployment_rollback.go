@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const changeCauseAnnotation = "kubernetes.io/change-cause"
+
+// DeploymentRevision deployment的一个历史版本,对应一个replicaset
+type DeploymentRevision struct {
+	Revision          int64     `json:"revision"`
+	ReplicaSet        string    `json:"replicaSet"`
+	ChangeCause       string    `json:"changeCause,omitempty"`
+	Images            []string  `json:"images"`
+	CreationTimestamp time.Time `json:"creationTimestamp"`
+}
+
+// 获取deployment的历史版本,按版本号从小到大排序
+func (d *deployment) History(ctx context.Context, name string) ([]DeploymentRevision, error) {
+	deploy, err := d.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	replicaSets, err := d.ownedReplicaSets(ctx, deploy)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]DeploymentRevision, 0, len(replicaSets))
+	for _, rs := range replicaSets {
+		revision, err := replicaSetRevision(&rs)
+		if err != nil {
+			continue
+		}
+
+		var images []string
+		for _, c := range rs.Spec.Template.Spec.Containers {
+			images = append(images, c.Image)
+		}
+		history = append(history, DeploymentRevision{
+			Revision:          revision,
+			ReplicaSet:        rs.Name,
+			ChangeCause:       rs.Annotations[changeCauseAnnotation],
+			Images:            images,
+			CreationTimestamp: rs.CreationTimestamp.Time,
+		})
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Revision < history[j].Revision
+	})
+	return history, nil
+}
+
+// 将deployment的pod模板恢复为指定版本,revision为0时回滚到上一个版本
+func (d *deployment) Rollback(ctx context.Context, name string, revision int64) (*v1.Deployment, error) {
+	deploy, err := d.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if deploy.Spec.Paused {
+		return nil, fmt.Errorf("cannot rollback paused deployment %s, resume it first", name)
+	}
+
+	replicaSets, err := d.ownedReplicaSets(ctx, deploy)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := rollbackTarget(replicaSets, revision)
+	if err != nil {
+		return nil, fmt.Errorf("deployment %s: %v", name, err)
+	}
+
+	template := target.Spec.Template.DeepCopy()
+	delete(template.Labels, v1.DefaultDeploymentUniqueLabelKey)
+	if apiequality.Semantic.DeepEqual(template, &deploy.Spec.Template) {
+		// 已经是目标版本,无需更新
+		return deploy, nil
+	}
+
+	deploy.Spec.Template = *template
+	if cause, ok := target.Annotations[changeCauseAnnotation]; ok {
+		if deploy.Annotations == nil {
+			deploy.Annotations = make(map[string]string)
+		}
+		deploy.Annotations[changeCauseAnnotation] = cause
+	}
+
+	updated, err := d.Update(ctx, deploy, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	d.event(updated, coreV1.EventTypeNormal, "Rollback", "Rolled back deployment %s to revision %s", name, target.Annotations[revisionAnnotation])
+	return updated, nil
+}
+
+// rollbackTarget 找到要回滚到的replicaset,revision为0时返回上一个版本
+func rollbackTarget(replicaSets []v1.ReplicaSet, revision int64) (*v1.ReplicaSet, error) {
+	var current, previous *v1.ReplicaSet
+	var currentRevision, previousRevision int64
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		r, err := replicaSetRevision(rs)
+		if err != nil {
+			continue
+		}
+
+		if revision > 0 {
+			if r == revision {
+				return rs, nil
+			}
+			continue
+		}
+
+		if r > currentRevision {
+			previous, previousRevision = current, currentRevision
+			current, currentRevision = rs, r
+		} else if r > previousRevision {
+			previous, previousRevision = rs, r
+		}
+	}
+
+	if revision > 0 {
+		return nil, fmt.Errorf("revision %d not found", revision)
+	}
+	if previous == nil {
+		return nil, fmt.Errorf("no previous revision to rollback to")
+	}
+	return previous, nil
+}
+
+func replicaSetRevision(rs *v1.ReplicaSet) (int64, error) {
+	return strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+}
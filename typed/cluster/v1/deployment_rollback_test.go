@@ -0,0 +1,39 @@
+package v1
+
+import (
+	v1 "k8s.io/api/apps/v1"
+	"testing"
+)
+
+func TestRollbackTarget(t *testing.T) {
+	replicaSets := make([]v1.ReplicaSet, 0, 3)
+	for _, revision := range []string{"3", "1", "2"} {
+		rs := v1.ReplicaSet{}
+		rs.Name = "app-" + revision
+		rs.Annotations = map[string]string{revisionAnnotation: revision}
+		replicaSets = append(replicaSets, rs)
+	}
+
+	rs, err := rollbackTarget(replicaSets, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Name != "app-2" {
+		t.Fatalf("previous revision is %s, want app-2", rs.Name)
+	}
+
+	rs, err = rollbackTarget(replicaSets, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Name != "app-1" {
+		t.Fatalf("revision 1 is %s, want app-1", rs.Name)
+	}
+
+	if _, err := rollbackTarget(replicaSets, 5); err == nil {
+		t.Fatal("expected error for missing revision")
+	}
+	if _, err := rollbackTarget(replicaSets[:1], 0); err == nil {
+		t.Fatal("expected error without previous revision")
+	}
+}
@@ -0,0 +1,181 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	autoscalingV1 "k8s.io/api/autoscaling/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"strconv"
+)
+
+// ScaleToZero之前的副本数记录在该注解中
+const restoreReplicasAnnotation = "k8s-client/restore-replicas"
+
+// HPAPolicy deployment被HPA管理时手动扩缩容的处理方式
+type HPAPolicy int
+
+const (
+	// 记录警告后继续扩缩容,HPA之后可能会覆盖副本数
+	HPAWarn HPAPolicy = iota
+	// 返回错误,不做扩缩容
+	HPAError
+)
+
+func (d *deployment) GetScale(ctx context.Context, name string) (*autoscalingV1.Scale, error) {
+	return d.client.AppsV1().
+		Deployments(d.ns).
+		GetScale(ctx, name, metav1.GetOptions{})
+}
+
+// 通过scale子资源修改副本数
+func (d *deployment) Scale(ctx context.Context, name string, replicas int32, policy HPAPolicy) (*autoscalingV1.Scale, error) {
+	if replicas > 0 {
+		hpa, err := d.findHPA(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if hpa != "" {
+			if policy == HPAError {
+				return nil, fmt.Errorf("deployment %s is managed by HorizontalPodAutoscaler %s", name, hpa)
+			}
+			klog.Warningf("deployment %s/%s is managed by HorizontalPodAutoscaler %s, replicas may be overridden", d.ns, name, hpa)
+		}
+	}
+
+	return d.scale(ctx, name, replicas)
+}
+
+// 暂停deployment的滚动更新
+func (d *deployment) Pause(ctx context.Context, name string) error {
+	return d.setPaused(ctx, name, true)
+}
+
+// 恢复deployment的滚动更新
+func (d *deployment) Resume(ctx context.Context, name string) error {
+	return d.setPaused(ctx, name, false)
+}
+
+// 将副本数缩容到0,并在注解中记录原来的副本数,用于RestoreScale恢复.
+// HPA在副本数为0时不会扩容
+func (d *deployment) ScaleToZero(ctx context.Context, name string) error {
+	scale, err := d.GetScale(ctx, name)
+	if err != nil {
+		return err
+	}
+	if scale.Spec.Replicas == 0 {
+		return nil
+	}
+
+	err = d.patchAnnotation(ctx, name, restoreReplicasAnnotation, strconv.Itoa(int(scale.Spec.Replicas)))
+	if err != nil {
+		return err
+	}
+
+	_, err = d.scale(ctx, name, 0)
+	return err
+}
+
+// 恢复ScaleToZero之前的副本数
+func (d *deployment) RestoreScale(ctx context.Context, name string) error {
+	deploy, err := d.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	value, ok := deploy.Annotations[restoreReplicasAnnotation]
+	if !ok {
+		return fmt.Errorf("deployment %s has no %s annotation", name, restoreReplicasAnnotation)
+	}
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid %s annotation %q: %v", restoreReplicasAnnotation, value, err)
+	}
+
+	if _, err := d.scale(ctx, name, int32(replicas)); err != nil {
+		return err
+	}
+
+	return d.removeAnnotation(ctx, name, restoreReplicasAnnotation)
+}
+
+func (d *deployment) scale(ctx context.Context, name string, replicas int32) (*autoscalingV1.Scale, error) {
+	scale, err := d.GetScale(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := scale.Spec.Replicas
+	scale.Spec.Replicas = replicas
+	updated, err := d.client.AppsV1().
+		Deployments(d.ns).
+		UpdateScale(ctx, name, scale, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if d.recording() && previous != replicas {
+		if deploy, err := d.Get(ctx, name, metav1.GetOptions{}); err == nil {
+			d.event(deploy, coreV1.EventTypeNormal, "Scale", "Scaled deployment %s from %d to %d", name, previous, replicas)
+		}
+	}
+	return updated, nil
+}
+
+// findHPA 返回管理该deployment的HPA名称
+func (d *deployment) findHPA(ctx context.Context, name string) (string, error) {
+	list, err := d.client.AutoscalingV2beta2().
+		HorizontalPodAutoscalers(d.ns).
+		List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	for _, hpa := range list.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		if ref.Kind == "Deployment" && ref.Name == name {
+			return hpa.Name, nil
+		}
+	}
+	return "", nil
+}
+
+func (d *deployment) setPaused(ctx context.Context, name string, paused bool) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"paused": paused},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.AppsV1().
+		Deployments(d.ns).
+		Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+	return err
+}
+
+func (d *deployment) patchAnnotation(ctx context.Context, name, key, value string) error {
+	return d.mergeAnnotations(ctx, name, map[string]interface{}{key: value})
+}
+
+func (d *deployment) removeAnnotation(ctx context.Context, name, key string) error {
+	// merge patch中null表示删除
+	return d.mergeAnnotations(ctx, name, map[string]interface{}{key: nil})
+}
+
+func (d *deployment) mergeAnnotations(ctx context.Context, name string, annotations map[string]interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.AppsV1().
+		Deployments(d.ns).
+		Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+	return err
+}
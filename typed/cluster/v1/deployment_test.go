@@ -63,3 +63,60 @@ func TestDeployment_ReDeploy(t *testing.T) {
 
 	t.Logf("update deployment successfully")
 }
+
+func TestDeployment_Rollback(t *testing.T) {
+	namespace := "dev1-xiaomai-server"
+	deploymentName := "dev1-app-forum-latest"
+
+	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := NewForConfig(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	history, err := client.Deployment(namespace).History(ctx, deploymentName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range history {
+		t.Logf("revision %d %s %v %s", h.Revision, h.ReplicaSet, h.Images, h.ChangeCause)
+	}
+
+	_, err = client.Deployment(namespace).Rollback(ctx, deploymentName, 0)
+	if err != nil {
+		t.Fatalf("rollback deployment fatalf: %v", err)
+	}
+}
+
+func TestDeployment_ScaleToZero(t *testing.T) {
+	namespace := "dev1-xiaomai-server"
+	deploymentName := "dev1-app-forum-latest"
+
+	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := NewForConfig(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = client.Deployment(namespace).ScaleToZero(ctx, deploymentName)
+	if err != nil {
+		t.Fatalf("scale deployment to zero fatalf: %v", err)
+	}
+
+	err = client.Deployment(namespace).RestoreScale(ctx, deploymentName)
+	if err != nil {
+		t.Fatalf("restore deployment scale fatalf: %v", err)
+	}
+
+	scale, err := client.Deployment(namespace).GetScale(ctx, deploymentName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("replicas : %d", scale.Spec.Replicas)
+}
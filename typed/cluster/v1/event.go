@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/reference"
+	"sort"
+	"time"
+)
+
+type EventsGetter interface {
+	Events(namespace string) EventsInterface
+}
+
+type EventsInterface interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Event, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.EventList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	EventsFor(ctx context.Context, obj runtime.Object) ([]v1.Event, error)
+}
+
+type events struct {
+	client *kubernetes.Clientset
+	ns     string
+}
+
+func newEvents(c *kubernetes.Clientset, namespace string) *events {
+	return &events{
+		client: c,
+		ns:     namespace,
+	}
+}
+
+func (e *events) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Event, error) {
+	return e.client.CoreV1().
+		Events(e.ns).
+		Get(ctx, name, opts)
+}
+
+func (e *events) List(ctx context.Context, opts metav1.ListOptions) (*v1.EventList, error) {
+	return e.client.CoreV1().
+		Events(e.ns).
+		List(ctx, opts)
+}
+
+func (e *events) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return e.client.CoreV1().
+		Events(e.ns).
+		Watch(ctx, opts)
+}
+
+// 获取对象(Deployment,Pod,HPA等)相关的事件,reason和message相同的事件合并计数,
+// 按最后发生时间倒序返回
+func (e *events) EventsFor(ctx context.Context, obj runtime.Object) ([]v1.Event, error) {
+	ref, err := reference.GetReference(scheme.Scheme, obj)
+	if err != nil {
+		return nil, fmt.Errorf("get reference of object err: %v", err)
+	}
+
+	selector := fields.Set{
+		"involvedObject.kind": ref.Kind,
+		"involvedObject.name": ref.Name,
+	}
+	if ref.UID != "" {
+		selector["involvedObject.uid"] = string(ref.UID)
+	}
+
+	namespace := e.ns
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	list, err := e.client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: selector.AsSelector().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dedupEvents(list.Items), nil
+}
+
+// dedupEvents 合并reason和message相同的事件
+func dedupEvents(items []v1.Event) []v1.Event {
+	type key struct {
+		eventType string
+		reason    string
+		message   string
+	}
+
+	index := make(map[key]int)
+	var merged []v1.Event
+	for _, item := range items {
+		k := key{eventType: item.Type, reason: item.Reason, message: item.Message}
+		count := item.Count
+		if count == 0 {
+			count = 1
+		}
+
+		i, ok := index[k]
+		if !ok {
+			item.Count = count
+			index[k] = len(merged)
+			merged = append(merged, item)
+			continue
+		}
+
+		m := &merged[i]
+		m.Count += count
+		if item.FirstTimestamp.Before(&m.FirstTimestamp) {
+			m.FirstTimestamp = item.FirstTimestamp
+		}
+		if eventTime(&item).After(eventTime(m)) {
+			m.LastTimestamp = item.LastTimestamp
+			m.EventTime = item.EventTime
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return eventTime(&merged[i]).After(eventTime(&merged[j]))
+	})
+	return merged
+}
+
+func eventTime(e *v1.Event) time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+	return e.FirstTimestamp.Time
+}
@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedupEvents(t *testing.T) {
+	now := time.Now()
+	items := []v1.Event{
+		{Type: v1.EventTypeWarning, Reason: "BackOff", Message: "Back-off restarting failed container", Count: 3, LastTimestamp: metav1.NewTime(now.Add(-time.Minute))},
+		{Type: v1.EventTypeNormal, Reason: "Pulled", Message: "Container image already present", Count: 1, LastTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+		{Type: v1.EventTypeWarning, Reason: "BackOff", Message: "Back-off restarting failed container", Count: 2, LastTimestamp: metav1.NewTime(now)},
+	}
+
+	merged := dedupEvents(items)
+	if len(merged) != 2 {
+		t.Fatalf("got %d events, want 2", len(merged))
+	}
+	if merged[0].Reason != "BackOff" || merged[0].Count != 5 {
+		t.Fatalf("unexpected first event: %s x%d", merged[0].Reason, merged[0].Count)
+	}
+	if !merged[0].LastTimestamp.Time.Equal(metav1.NewTime(now).Time) {
+		t.Fatalf("last timestamp not merged: %v", merged[0].LastTimestamp)
+	}
+}
+
+func TestEvents_EventsFor(t *testing.T) {
+	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := NewForConfig(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	namespace := "dev1-xiaomai-server"
+	deploy, err := client.Deployment(namespace).Get(ctx, "dev1-app-forum-latest", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := client.Events(namespace).EventsFor(ctx, deploy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range events {
+		t.Logf("%s %s (x%d): %s", e.Type, e.Reason, e.Count, e.Message)
+	}
+}
+
+func TestEventRecorderStop(t *testing.T) {
+	r := &eventRecorder{}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-forum"}}
+
+	// 未开启时丢弃事件
+	r.Event(pod, v1.EventTypeNormal, "Test", "before start")
+	if r.enabled() {
+		t.Fatal("recorder should be disabled before start")
+	}
+
+	broadcaster := record.NewBroadcaster()
+	r.start(broadcaster, broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "test"}))
+	if !r.enabled() {
+		t.Fatal("recorder should be enabled after start")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r.Eventf(pod, v1.EventTypeNormal, "Test", "event %d", j)
+			}
+		}()
+	}
+	r.stop(broadcaster)
+	wg.Wait()
+
+	// 停止之后记录事件不会写入已经关闭的broadcaster
+	r.Event(pod, v1.EventTypeNormal, "Test", "after stop")
+	if r.enabled() {
+		t.Fatal("recorder should be disabled after stop")
+	}
+}
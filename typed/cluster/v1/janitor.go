@@ -0,0 +1,336 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	coreV1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/reference"
+	"k8s.io/klog"
+	"strconv"
+	"time"
+)
+
+const (
+	// 过期时间,RFC3339格式,例如2020-08-01T00:00:00+08:00
+	ExpiresAtAnnotation = "k8s-client/expires-at"
+	// label的值不能包含冒号,使用unix时间戳或2006-01-02格式
+	ExpiresAtLabel = "k8s-client/expires-at"
+
+	// 已经发出警告的过期时间,过期时间被延长后会重新警告
+	expiryWarnedAnnotation = "k8s-client/expiry-warned"
+	// 已经缩容的过期时间,避免和手动扩容冲突
+	expiryScaledDownAnnotation = "k8s-client/expiry-scaled-down"
+
+	defaultJanitorInterval = 10 * time.Minute
+	janitorComponent       = "k8s-client-janitor"
+)
+
+// JanitorAction 对过期对象的处理方式
+type JanitorAction string
+
+const (
+	JanitorScaleDown JanitorAction = "ScaleDown"
+	JanitorDelete    JanitorAction = "Delete"
+	JanitorWarn      JanitorAction = "Warn"
+)
+
+type JanitorOptions struct {
+	// 过期后的处理方式,默认ScaleDown
+	Action JanitorAction
+	// 过期之前多久发出警告事件,为0时不警告
+	WarnBefore time.Duration
+	// 同时检查所有namespace中的Deployment
+	IncludeDeployments bool
+	// 只检查匹配的namespace
+	LabelSelector string
+	// Run的检查间隔,默认10分钟
+	Interval time.Duration
+	// 只生成报告,不做任何修改
+	DryRun bool
+}
+
+// JanitorItem 一个对象的处理结果,Kind为Namespace时Namespace为空
+type JanitorItem struct {
+	Kind      string
+	Namespace string
+	Name      string
+	ExpiresAt time.Time
+	Action    JanitorAction
+	Err       error
+}
+
+type JanitorReport struct {
+	DryRun bool
+	Items  []JanitorItem
+}
+
+// Janitor 清理过期的临时namespace和Deployment
+type Janitor struct {
+	cluster *Cluster
+	opts    JanitorOptions
+	now     func() time.Time
+}
+
+func (c *Cluster) Janitor(opts JanitorOptions) *Janitor {
+	if opts.Action == "" {
+		opts.Action = JanitorScaleDown
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultJanitorInterval
+	}
+
+	return &Janitor{
+		cluster: c,
+		opts:    opts,
+		now:     time.Now,
+	}
+}
+
+// 按Interval循环执行RunOnce,直到ctx结束
+func (j *Janitor) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		report, err := j.RunOnce(ctx)
+		if err != nil {
+			klog.Errorf("janitor run err: %v", err)
+			return
+		}
+		for _, item := range report.Items {
+			if item.Err != nil {
+				klog.Errorf("janitor %s %s %s/%s err: %v", item.Action, item.Kind, item.Namespace, item.Name, item.Err)
+			}
+		}
+	}, j.opts.Interval)
+}
+
+// 多副本部署时只有leader执行清理,lockNamespace为Lease所在的namespace
+func (j *Janitor) RunWithLeaderElection(ctx context.Context, lockName, lockNamespace string) error {
+	return j.cluster.RunWithLeaderElection(ctx, lockName, lockNamespace, j.Run, nil)
+}
+
+// 检查一次所有namespace和Deployment
+func (j *Janitor) RunOnce(ctx context.Context) (*JanitorReport, error) {
+	report := &JanitorReport{DryRun: j.opts.DryRun}
+
+	list, err := j.cluster.client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: j.opts.LabelSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range list.Items {
+		ns := &list.Items[i]
+		if ns.Status.Phase == coreV1.NamespaceTerminating {
+			continue
+		}
+
+		expired := false
+		if expiresAt, ok := expiryOf(&ns.ObjectMeta); ok {
+			action := j.actionFor(&ns.ObjectMeta, expiresAt)
+			if action != "" {
+				item := JanitorItem{Kind: "Namespace", Name: ns.Name, ExpiresAt: expiresAt, Action: action}
+				if !j.opts.DryRun {
+					item.Err = j.handleNamespace(ctx, ns, action, expiresAt)
+				}
+				report.Items = append(report.Items, item)
+			}
+			expired = !j.now().Before(expiresAt)
+		}
+
+		// 过期namespace中的Deployment已经一起处理
+		if j.opts.IncludeDeployments && !expired {
+			if err := j.checkDeployments(ctx, ns.Name, report); err != nil {
+				report.Items = append(report.Items, JanitorItem{Kind: "Deployment", Namespace: ns.Name, Err: err})
+			}
+		}
+	}
+	return report, nil
+}
+
+func (j *Janitor) checkDeployments(ctx context.Context, namespace string, report *JanitorReport) error {
+	d := newDeployment(j.cluster.client, namespace, j.cluster.recorder)
+	list, err := d.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		deploy := &list.Items[i]
+		expiresAt, ok := expiryOf(&deploy.ObjectMeta)
+		if !ok {
+			continue
+		}
+		action := j.actionFor(&deploy.ObjectMeta, expiresAt)
+		if action == "" {
+			continue
+		}
+
+		item := JanitorItem{Kind: "Deployment", Namespace: namespace, Name: deploy.Name, ExpiresAt: expiresAt, Action: action}
+		if !j.opts.DryRun {
+			item.Err = j.handleDeployment(ctx, d, deploy.Name, action, expiresAt)
+		}
+		report.Items = append(report.Items, item)
+	}
+	return nil
+}
+
+// actionFor 返回需要执行的操作,不需要处理时返回空
+func (j *Janitor) actionFor(meta *metav1.ObjectMeta, expiresAt time.Time) JanitorAction {
+	now := j.now()
+	stamp := expiresAt.UTC().Format(time.RFC3339)
+
+	if !now.Before(expiresAt) {
+		// 缩容和警告每个过期时间只执行一次,过期前已经警告过的不再重复警告
+		if j.opts.Action == JanitorScaleDown && meta.Annotations[expiryScaledDownAnnotation] == stamp {
+			return ""
+		}
+		if j.opts.Action == JanitorWarn && meta.Annotations[expiryWarnedAnnotation] == stamp {
+			return ""
+		}
+		return j.opts.Action
+	}
+
+	if j.opts.WarnBefore > 0 && !now.Before(expiresAt.Add(-j.opts.WarnBefore)) && meta.Annotations[expiryWarnedAnnotation] != stamp {
+		return JanitorWarn
+	}
+	return ""
+}
+
+func (j *Janitor) handleNamespace(ctx context.Context, ns *coreV1.Namespace, action JanitorAction, expiresAt time.Time) error {
+	stamp := expiresAt.UTC().Format(time.RFC3339)
+	switch action {
+	case JanitorWarn:
+		if err := j.warn(ctx, "Namespace", ns, &ns.ObjectMeta, expiresAt); err != nil {
+			return err
+		}
+		return j.patchNamespaceAnnotation(ctx, ns.Name, expiryWarnedAnnotation, stamp)
+	case JanitorDelete:
+		err := newNamespaces(j.cluster.client).Delete(ctx, ns.Name, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	case JanitorScaleDown:
+		d := newDeployment(j.cluster.client, ns.Name, j.cluster.recorder)
+		list, err := d.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for _, deploy := range list.Items {
+			if err := d.ScaleToZero(ctx, deploy.Name); err != nil {
+				return err
+			}
+		}
+		return j.patchNamespaceAnnotation(ctx, ns.Name, expiryScaledDownAnnotation, stamp)
+	}
+	return fmt.Errorf("unknown janitor action %s", action)
+}
+
+func (j *Janitor) handleDeployment(ctx context.Context, d *deployment, name string, action JanitorAction, expiresAt time.Time) error {
+	stamp := expiresAt.UTC().Format(time.RFC3339)
+	switch action {
+	case JanitorWarn:
+		deploy, err := d.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if err := j.warn(ctx, "Deployment", deploy, &deploy.ObjectMeta, expiresAt); err != nil {
+			return err
+		}
+		return d.patchAnnotation(ctx, name, expiryWarnedAnnotation, stamp)
+	case JanitorDelete:
+		err := d.Delete(ctx, name, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	case JanitorScaleDown:
+		if err := d.ScaleToZero(ctx, name); err != nil {
+			return err
+		}
+		return d.patchAnnotation(ctx, name, expiryScaledDownAnnotation, stamp)
+	}
+	return fmt.Errorf("unknown janitor action %s", action)
+}
+
+// warn 同步创建警告事件,不依赖RecordEvents.事件创建成功后才标记为已警告,
+// 失败时下次检查会重新警告
+func (j *Janitor) warn(ctx context.Context, kind string, obj runtime.Object, meta *metav1.ObjectMeta, expiresAt time.Time) error {
+	message := fmt.Sprintf("Expires at %s, will be handled by %s", expiresAt.Format(time.RFC3339), j.opts.Action)
+	klog.Warningf("%s %s/%s: %s", kind, meta.Namespace, meta.Name, message)
+
+	event, err := expiryEvent(obj, message, j.now())
+	if err != nil {
+		return err
+	}
+	_, err = j.cluster.client.CoreV1().Events(event.Namespace).Create(ctx, event, metav1.CreateOptions{})
+	return err
+}
+
+// expiryEvent 和EventRecorder生成的事件一致,集群级别的对象的事件放在default namespace中
+func expiryEvent(obj runtime.Object, message string, now time.Time) (*coreV1.Event, error) {
+	ref, err := reference.GetReference(scheme.Scheme, obj)
+	if err != nil {
+		return nil, err
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	timestamp := metav1.NewTime(now)
+	return &coreV1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", ref.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         "ExpiringSoon",
+		Message:        message,
+		Type:           coreV1.EventTypeWarning,
+		Source:         coreV1.EventSource{Component: janitorComponent},
+		FirstTimestamp: timestamp,
+		LastTimestamp:  timestamp,
+		Count:          1,
+	}, nil
+}
+
+func (j *Janitor) patchNamespaceAnnotation(ctx context.Context, name, key, value string) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{key: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = newNamespaces(j.cluster.client).Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+	return err
+}
+
+// expiryOf 读取对象的过期时间,注解优先于label
+func expiryOf(meta *metav1.ObjectMeta) (time.Time, bool) {
+	if value, ok := meta.Annotations[ExpiresAtAnnotation]; ok {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, true
+		}
+		klog.Warningf("invalid %s annotation %q on %s/%s", ExpiresAtAnnotation, value, meta.Namespace, meta.Name)
+	}
+
+	if value, ok := meta.Labels[ExpiresAtLabel]; ok {
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Unix(seconds, 0), true
+		}
+		if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+			return t, true
+		}
+		klog.Warningf("invalid %s label %q on %s/%s", ExpiresAtLabel, value, meta.Namespace, meta.Name)
+	}
+	return time.Time{}, false
+}
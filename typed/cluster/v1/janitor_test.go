@@ -0,0 +1,99 @@
+package v1
+
+import (
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpiryOf(t *testing.T) {
+	expiresAt := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		meta metav1.ObjectMeta
+		ok   bool
+	}{
+		{metav1.ObjectMeta{Annotations: map[string]string{ExpiresAtAnnotation: expiresAt.Format(time.RFC3339)}}, true},
+		{metav1.ObjectMeta{Labels: map[string]string{ExpiresAtLabel: strconv.FormatInt(expiresAt.Unix(), 10)}}, true},
+		{metav1.ObjectMeta{Annotations: map[string]string{ExpiresAtAnnotation: "tomorrow"}}, false},
+		{metav1.ObjectMeta{}, false},
+	}
+	for i, c := range cases {
+		got, ok := expiryOf(&c.meta)
+		if ok != c.ok {
+			t.Fatalf("case %d: ok = %v, want %v", i, ok, c.ok)
+		}
+		if ok && !got.Equal(expiresAt) {
+			t.Fatalf("case %d: expires at %v, want %v", i, got, expiresAt)
+		}
+	}
+
+	meta := metav1.ObjectMeta{Labels: map[string]string{ExpiresAtLabel: "2020-08-01"}}
+	if got, ok := expiryOf(&meta); !ok || got.Format("2006-01-02") != "2020-08-01" {
+		t.Fatalf("date label = %v, %v", got, ok)
+	}
+}
+
+func TestJanitor_ActionFor(t *testing.T) {
+	expiresAt := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+	stamp := expiresAt.Format(time.RFC3339)
+
+	j := (&Cluster{}).Janitor(JanitorOptions{WarnBefore: 24 * time.Hour})
+
+	cases := []struct {
+		now         time.Time
+		annotations map[string]string
+		want        JanitorAction
+	}{
+		{expiresAt.Add(-48 * time.Hour), nil, ""},
+		{expiresAt.Add(-time.Hour), nil, JanitorWarn},
+		{expiresAt.Add(-time.Hour), map[string]string{expiryWarnedAnnotation: stamp}, ""},
+		{expiresAt.Add(time.Hour), nil, JanitorScaleDown},
+		{expiresAt.Add(time.Hour), map[string]string{expiryScaledDownAnnotation: stamp}, ""},
+	}
+	for i, c := range cases {
+		now := c.now
+		j.now = func() time.Time { return now }
+		if got := j.actionFor(&metav1.ObjectMeta{Annotations: c.annotations}, expiresAt); got != c.want {
+			t.Fatalf("case %d: action = %q, want %q", i, got, c.want)
+		}
+	}
+
+	j.opts.Action = JanitorDelete
+	j.now = func() time.Time { return expiresAt.Add(time.Hour) }
+	if got := j.actionFor(&metav1.ObjectMeta{Annotations: map[string]string{expiryScaledDownAnnotation: stamp}}, expiresAt); got != JanitorDelete {
+		t.Fatalf("action = %q, want %q", got, JanitorDelete)
+	}
+
+	// 过期后只警告一次
+	j.opts.Action = JanitorWarn
+	if got := j.actionFor(&metav1.ObjectMeta{}, expiresAt); got != JanitorWarn {
+		t.Fatalf("action = %q, want %q", got, JanitorWarn)
+	}
+	if got := j.actionFor(&metav1.ObjectMeta{Annotations: map[string]string{expiryWarnedAnnotation: stamp}}, expiresAt); got != "" {
+		t.Fatalf("expired object warned again: %q", got)
+	}
+}
+
+func TestExpiryEvent(t *testing.T) {
+	now := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	ns := &coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev1-feature-x", UID: "1234"}}
+	event, err := expiryEvent(ns, "Expires soon", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Namespace != metav1.NamespaceDefault || event.InvolvedObject.Kind != "Namespace" || event.InvolvedObject.Name != ns.Name {
+		t.Fatalf("event = %+v", event)
+	}
+	if event.Type != coreV1.EventTypeWarning || event.Reason != "ExpiringSoon" || event.Count != 1 {
+		t.Fatalf("event = %+v", event)
+	}
+
+	pod := &coreV1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "dev1-feature-x", Name: "app-forum"}}
+	if event, err = expiryEvent(pod, "Expires soon", now); err != nil || event.Namespace != "dev1-feature-x" {
+		t.Fatalf("event = %+v, %v", event, err)
+	}
+}
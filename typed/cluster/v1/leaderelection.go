@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// LeaderElector 通过coordination.k8s.io的Lease选主,
+// 同时实现了http.Handler,可以作为readiness探针,只有leader返回200
+type LeaderElector struct {
+	name     string
+	identity string
+	elector  *leaderelection.LeaderElector
+	leading  int32
+}
+
+// 创建选主,onStart在成为leader后调用,ctx在失去leader时结束.
+// onStop在失去leader或Run结束时调用,可以为nil.
+// identity为POD_NAME环境变量或主机名加上随机后缀
+func (c *Cluster) LeaderElector(name, namespace string, onStart func(ctx context.Context), onStop func()) (*LeaderElector, error) {
+	identity, err := leaderIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &LeaderElector{
+		name:     name,
+		identity: identity,
+	}
+	l.elector, err = leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Client:     c.client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity, EventRecorder: c.recorder},
+		},
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				atomic.StoreInt32(&l.leading, 1)
+				klog.Infof("%s became leader of %s/%s", identity, namespace, name)
+				onStart(ctx)
+			},
+			OnStoppedLeading: func() {
+				atomic.StoreInt32(&l.leading, 0)
+				klog.Infof("%s stopped leading %s/%s", identity, namespace, name)
+				if onStop != nil {
+					onStop()
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// 阻塞直到ctx结束或失去leader,失去leader后不会重新参与选主
+func (l *LeaderElector) Run(ctx context.Context) {
+	l.elector.Run(ctx)
+}
+
+func (l *LeaderElector) IsLeader() bool {
+	return atomic.LoadInt32(&l.leading) == 1
+}
+
+func (l *LeaderElector) Identity() string {
+	return l.identity
+}
+
+func (l *LeaderElector) Name() string {
+	return l.name
+}
+
+// 当前实例不是leader时返回错误,可以用于healthz检查
+func (l *LeaderElector) Check(_ *http.Request) error {
+	if !l.IsLeader() {
+		return fmt.Errorf("%s is not the leader of %s", l.identity, l.name)
+	}
+	return nil
+}
+
+func (l *LeaderElector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := l.Check(r); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// 选主并在成为leader后执行onStart,阻塞直到ctx结束或失去leader
+func (c *Cluster) RunWithLeaderElection(ctx context.Context, name, namespace string, onStart func(ctx context.Context), onStop func()) error {
+	l, err := c.LeaderElector(name, namespace, onStart, onStop)
+	if err != nil {
+		return err
+	}
+
+	l.Run(ctx)
+	return nil
+}
+
+// leaderIdentity 同一台主机上可能运行多个实例,加上随机后缀区分
+func leaderIdentity() (string, error) {
+	id := os.Getenv("POD_NAME")
+	if id == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", err
+		}
+		id = hostname
+	}
+	return id + "_" + string(uuid.NewUUID()), nil
+}
@@ -0,0 +1,40 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLeaderIdentity(t *testing.T) {
+	os.Setenv("POD_NAME", "app-forum-5d9b6f84fb-g2pl6")
+	defer os.Unsetenv("POD_NAME")
+
+	first, err := leaderIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := leaderIdentity()
+	if !strings.HasPrefix(first, "app-forum-5d9b6f84fb-g2pl6_") || first == second {
+		t.Fatalf("identities = %s, %s", first, second)
+	}
+}
+
+func TestLeaderElector_ServeHTTP(t *testing.T) {
+	l := &LeaderElector{name: "janitor", identity: "app-forum"}
+
+	w := httptest.NewRecorder()
+	l.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("follower status = %d", w.Code)
+	}
+
+	l.leading = 1
+	w = httptest.NewRecorder()
+	l.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("leader status = %d", w.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+type NamespacesGetter interface {
+	Namespaces() NamespaceInterface
+}
+
+type NamespaceInterface interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Namespace, error)
+	Create(ctx context.Context, namespace *v1.Namespace, opts metav1.CreateOptions) (*v1.Namespace, error)
+	Update(ctx context.Context, namespace *v1.Namespace, opts metav1.UpdateOptions) (*v1.Namespace, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.NamespaceList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) (*v1.Namespace, error)
+}
+
+type namespaces struct {
+	client *kubernetes.Clientset
+}
+
+func newNamespaces(c *kubernetes.Clientset) *namespaces {
+	return &namespaces{
+		client: c,
+	}
+}
+
+func (n *namespaces) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Namespace, error) {
+	return n.client.CoreV1().
+		Namespaces().
+		Get(ctx, name, opts)
+}
+
+func (n *namespaces) Create(ctx context.Context, namespace *v1.Namespace, opts metav1.CreateOptions) (*v1.Namespace, error) {
+	return n.client.CoreV1().
+		Namespaces().
+		Create(ctx, namespace, opts)
+}
+
+func (n *namespaces) Update(ctx context.Context, namespace *v1.Namespace, opts metav1.UpdateOptions) (*v1.Namespace, error) {
+	return n.client.CoreV1().
+		Namespaces().
+		Update(ctx, namespace, opts)
+}
+
+func (n *namespaces) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return n.client.CoreV1().
+		Namespaces().
+		Delete(ctx, name, &opts)
+}
+
+func (n *namespaces) List(ctx context.Context, opts metav1.ListOptions) (*v1.NamespaceList, error) {
+	return n.client.CoreV1().
+		Namespaces().
+		List(ctx, opts)
+}
+
+func (n *namespaces) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return n.client.CoreV1().
+		Namespaces().
+		Watch(ctx, opts)
+}
+
+func (n *namespaces) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) (*v1.Namespace, error) {
+	return n.client.CoreV1().
+		Namespaces().
+		Patch(ctx, name, pt, data, opts)
+}
@@ -0,0 +1,365 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	monitoringV1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	montiroingV1 "github.com/vperson/k8s-client/typed/montiroing/v1"
+	appsV1 "k8s.io/api/apps/v1"
+	"k8s.io/api/autoscaling/v2beta2"
+	coreV1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// CloneTransform 在创建之前修改对象,例如替换名称,域名或环境变量.
+// obj为*appsV1.Deployment,*coreV1.Service,*coreV1.ConfigMap,*coreV1.Secret,
+// *v2beta2.HorizontalPodAutoscaler或*monitoringV1.ServiceMonitor,返回false时跳过该对象
+type CloneTransform func(obj runtime.Object) (bool, error)
+
+type CloneOptions struct {
+	// 只复制匹配的对象
+	LabelSelector string
+	Transform     CloneTransform
+	// 目标namespace中已存在的对象会被覆盖,默认跳过
+	Overwrite bool
+	// 用于复制ServiceMonitor,例如ClientSet.MonitoringV1(),为nil时不复制ServiceMonitor
+	ServiceMonitors montiroingV1.ServiceMonitorsGetter
+}
+
+// ClonedObject 单个对象的复制结果
+type ClonedObject struct {
+	Kind string
+	Name string
+	Err  error
+}
+
+// CloneReport 复制的结果,Skipped包括目标中已存在和被Transform跳过的对象
+type CloneReport struct {
+	Cloned  []ClonedObject
+	Skipped []ClonedObject
+	Failed  []ClonedObject
+}
+
+// 所有失败的对象合并为一个错误,全部成功时返回nil
+func (r *CloneReport) Err() error {
+	var errs []error
+	for _, o := range r.Failed {
+		errs = append(errs, fmt.Errorf("%s %s: %v", o.Kind, o.Name, o.Err))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// 将src中的Deployment,Service,ConfigMap,Secret,HPA和ServiceMonitor(需要opts.ServiceMonitors)复制到dst,
+// dst不存在时自动创建.去掉resourceVersion,uid,status,clusterIP,nodePort等由集群分配的字段,
+// ServiceAccount的token Secret由集群生成,不会被复制.单个对象失败不影响其他对象
+func (c *Cluster) CloneNamespace(ctx context.Context, src, dst string, opts CloneOptions) (*CloneReport, error) {
+	if src == dst {
+		return nil, fmt.Errorf("source and destination namespace are both %s", src)
+	}
+	if err := c.ensureNamespace(ctx, src, dst); err != nil {
+		return nil, err
+	}
+
+	cloner := &namespaceCloner{
+		cluster: c,
+		src:     src,
+		dst:     dst,
+		opts:    opts,
+		report:  &CloneReport{},
+	}
+	steps := []func(ctx context.Context) error{
+		cloner.configMaps,
+		cloner.secrets,
+		cloner.services,
+		cloner.deployments,
+		cloner.horizontalPodAutoscalers,
+		cloner.serviceMonitors,
+	}
+	for _, step := range steps {
+		if err := step(ctx); err != nil {
+			return cloner.report, err
+		}
+	}
+	return cloner.report, nil
+}
+
+// ensureNamespace 创建dst,labels和annotations从src复制
+func (c *Cluster) ensureNamespace(ctx context.Context, src, dst string) error {
+	ns := newNamespaces(c.client)
+	source, err := ns.Get(ctx, src, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = ns.Get(ctx, dst, metav1.GetOptions{})
+	if err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	target := &coreV1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        dst,
+			Labels:      copyLabels(source.Labels),
+			Annotations: copyLabels(source.Annotations),
+		},
+	}
+	delete(target.Annotations, lastAppliedAnnotation)
+	_, err = ns.Create(ctx, target, metav1.CreateOptions{})
+	return err
+}
+
+type namespaceCloner struct {
+	cluster *Cluster
+	src     string
+	dst     string
+	opts    CloneOptions
+	report  *CloneReport
+}
+
+func (n *namespaceCloner) listOptions() metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: n.opts.LabelSelector}
+}
+
+func (n *namespaceCloner) configMaps(ctx context.Context) error {
+	client := n.cluster.client.CoreV1().ConfigMaps(n.dst)
+	list, err := n.cluster.client.CoreV1().ConfigMaps(n.src).List(ctx, n.listOptions())
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		obj := list.Items[i].DeepCopy()
+		n.clone("ConfigMap", obj, &obj.ObjectMeta, func() error {
+			_, err := client.Create(ctx, obj, metav1.CreateOptions{})
+			return err
+		}, func() error {
+			existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			obj.ResourceVersion = existing.ResourceVersion
+			_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+			return err
+		})
+	}
+	return nil
+}
+
+func (n *namespaceCloner) secrets(ctx context.Context) error {
+	client := n.cluster.client.CoreV1().Secrets(n.dst)
+	list, err := n.cluster.client.CoreV1().Secrets(n.src).List(ctx, n.listOptions())
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		if list.Items[i].Type == coreV1.SecretTypeServiceAccountToken {
+			continue
+		}
+
+		obj := list.Items[i].DeepCopy()
+		n.clone("Secret", obj, &obj.ObjectMeta, func() error {
+			_, err := client.Create(ctx, obj, metav1.CreateOptions{})
+			return err
+		}, func() error {
+			existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			obj.ResourceVersion = existing.ResourceVersion
+			_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+			return err
+		})
+	}
+	return nil
+}
+
+func (n *namespaceCloner) services(ctx context.Context) error {
+	client := n.cluster.client.CoreV1().Services(n.dst)
+	list, err := n.cluster.client.CoreV1().Services(n.src).List(ctx, n.listOptions())
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		obj := list.Items[i].DeepCopy()
+		stripService(obj)
+		n.clone("Service", obj, &obj.ObjectMeta, func() error {
+			_, err := client.Create(ctx, obj, metav1.CreateOptions{})
+			return err
+		}, func() error {
+			existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			// clusterIP不能修改
+			obj.ResourceVersion = existing.ResourceVersion
+			obj.Spec.ClusterIP = existing.Spec.ClusterIP
+			_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+			return err
+		})
+	}
+	return nil
+}
+
+func (n *namespaceCloner) deployments(ctx context.Context) error {
+	client := n.cluster.client.AppsV1().Deployments(n.dst)
+	list, err := n.cluster.client.AppsV1().Deployments(n.src).List(ctx, n.listOptions())
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		obj := list.Items[i].DeepCopy()
+		obj.Status = appsV1.DeploymentStatus{}
+		delete(obj.Annotations, revisionAnnotation)
+		n.clone("Deployment", obj, &obj.ObjectMeta, func() error {
+			_, err := client.Create(ctx, obj, metav1.CreateOptions{})
+			return err
+		}, func() error {
+			existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			obj.ResourceVersion = existing.ResourceVersion
+			_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+			return err
+		})
+	}
+	return nil
+}
+
+func (n *namespaceCloner) horizontalPodAutoscalers(ctx context.Context) error {
+	client := n.cluster.client.AutoscalingV2beta2().HorizontalPodAutoscalers(n.dst)
+	list, err := n.cluster.client.AutoscalingV2beta2().HorizontalPodAutoscalers(n.src).List(ctx, n.listOptions())
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		obj := list.Items[i].DeepCopy()
+		obj.Status = v2beta2.HorizontalPodAutoscalerStatus{}
+		n.clone("HorizontalPodAutoscaler", obj, &obj.ObjectMeta, func() error {
+			_, err := client.Create(ctx, obj, metav1.CreateOptions{})
+			return err
+		}, func() error {
+			existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			obj.ResourceVersion = existing.ResourceVersion
+			_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+			return err
+		})
+	}
+	return nil
+}
+
+// serviceMonitors 没有传入ServiceMonitors或集群中没有安装prometheus-operator时跳过
+func (n *namespaceCloner) serviceMonitors(ctx context.Context) error {
+	if n.opts.ServiceMonitors == nil {
+		return nil
+	}
+
+	client := n.opts.ServiceMonitors.ServiceMonitors(n.dst)
+	list, err := n.opts.ServiceMonitors.ServiceMonitors(n.src).List(ctx, n.listOptions())
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	for i := range list.Items {
+		obj := list.Items[i].DeepCopy()
+		rewriteNamespaceSelector(&obj.Spec.NamespaceSelector, n.src, n.dst)
+		n.clone("ServiceMonitor", obj, &obj.ObjectMeta, func() error {
+			_, err := client.Create(ctx, obj, metav1.CreateOptions{})
+			return err
+		}, func() error {
+			existing, err := client.Get(ctx, obj.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			obj.ResourceVersion = existing.ResourceVersion
+			_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+			return err
+		})
+	}
+	return nil
+}
+
+// clone 去掉集群分配的字段,执行Transform后创建对象,已存在时根据Overwrite更新或跳过
+func (n *namespaceCloner) clone(kind string, obj runtime.Object, meta *metav1.ObjectMeta, create, update func() error) {
+	stripObjectMeta(meta, n.dst)
+
+	if n.opts.Transform != nil {
+		keep, err := n.opts.Transform(obj)
+		if err != nil {
+			n.report.Failed = append(n.report.Failed, ClonedObject{Kind: kind, Name: meta.Name, Err: err})
+			return
+		}
+		if !keep {
+			n.report.Skipped = append(n.report.Skipped, ClonedObject{Kind: kind, Name: meta.Name})
+			return
+		}
+		// Transform不能把对象移到其他namespace
+		meta.Namespace = n.dst
+	}
+
+	err := create()
+	if apierrors.IsAlreadyExists(err) {
+		if !n.opts.Overwrite {
+			n.report.Skipped = append(n.report.Skipped, ClonedObject{Kind: kind, Name: meta.Name})
+			return
+		}
+		err = update()
+	}
+	if err != nil {
+		n.report.Failed = append(n.report.Failed, ClonedObject{Kind: kind, Name: meta.Name, Err: err})
+		return
+	}
+	n.report.Cloned = append(n.report.Cloned, ClonedObject{Kind: kind, Name: meta.Name})
+}
+
+// stripObjectMeta 去掉由集群分配的metadata字段
+func stripObjectMeta(meta *metav1.ObjectMeta, namespace string) {
+	meta.Namespace = namespace
+	meta.ResourceVersion = ""
+	meta.UID = ""
+	meta.SelfLink = ""
+	meta.Generation = 0
+	meta.CreationTimestamp = metav1.Time{}
+	meta.DeletionTimestamp = nil
+	meta.DeletionGracePeriodSeconds = nil
+	meta.ManagedFields = nil
+	meta.OwnerReferences = nil
+	meta.Finalizers = nil
+	delete(meta.Annotations, lastAppliedAnnotation)
+}
+
+// stripService 去掉由集群分配的clusterIP和nodePort,headless的Service保留None
+func stripService(svc *coreV1.Service) {
+	svc.Status = coreV1.ServiceStatus{}
+	if svc.Spec.ClusterIP != coreV1.ClusterIPNone {
+		svc.Spec.ClusterIP = ""
+	}
+	svc.Spec.HealthCheckNodePort = 0
+	for i := range svc.Spec.Ports {
+		svc.Spec.Ports[i].NodePort = 0
+	}
+}
+
+// rewriteNamespaceSelector 将ServiceMonitor选择的src替换为dst
+func rewriteNamespaceSelector(selector *monitoringV1.NamespaceSelector, src, dst string) {
+	for i, name := range selector.MatchNames {
+		if name == src {
+			selector.MatchNames[i] = dst
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	monitoringV1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	montiroingV1 "github.com/vperson/k8s-client/typed/montiroing/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"testing"
+)
+
+func TestStripService(t *testing.T) {
+	svc := &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "app-forum",
+			Namespace:       "dev1-xiaomai-server",
+			ResourceVersion: "123",
+			UID:             "uid",
+			Annotations:     map[string]string{lastAppliedAnnotation: "{}", "owner": "xiaomai"},
+		},
+		Spec: coreV1.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Ports:     []coreV1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}},
+		},
+	}
+
+	stripObjectMeta(&svc.ObjectMeta, "dev2-xiaomai-server")
+	stripService(svc)
+
+	if svc.Namespace != "dev2-xiaomai-server" || svc.ResourceVersion != "" || svc.UID != "" {
+		t.Fatalf("metadata not stripped: %+v", svc.ObjectMeta)
+	}
+	if _, ok := svc.Annotations[lastAppliedAnnotation]; ok {
+		t.Fatal("last applied annotation should be removed")
+	}
+	if svc.Annotations["owner"] != "xiaomai" {
+		t.Fatal("user annotation should be kept")
+	}
+	if svc.Spec.ClusterIP != "" || svc.Spec.Ports[0].NodePort != 0 {
+		t.Fatalf("cluster assigned fields not stripped: %+v", svc.Spec)
+	}
+
+	headless := &coreV1.Service{Spec: coreV1.ServiceSpec{ClusterIP: coreV1.ClusterIPNone}}
+	stripService(headless)
+	if headless.Spec.ClusterIP != coreV1.ClusterIPNone {
+		t.Fatal("headless service should keep clusterIP None")
+	}
+}
+
+func TestRewriteNamespaceSelector(t *testing.T) {
+	selector := monitoringV1.NamespaceSelector{MatchNames: []string{"dev1-xiaomai-server", "monitoring"}}
+	rewriteNamespaceSelector(&selector, "dev1-xiaomai-server", "dev2-xiaomai-server")
+	if selector.MatchNames[0] != "dev2-xiaomai-server" || selector.MatchNames[1] != "monitoring" {
+		t.Fatalf("match names = %v", selector.MatchNames)
+	}
+}
+
+func TestNamespaceCloner_Clone(t *testing.T) {
+	n := &namespaceCloner{
+		dst: "dev2-xiaomai-server",
+		opts: CloneOptions{
+			Transform: func(obj runtime.Object) (bool, error) {
+				cm := obj.(*coreV1.ConfigMap)
+				switch cm.Name {
+				case "skip":
+					return false, nil
+				case "bad":
+					return false, fmt.Errorf("bad config")
+				}
+				cm.Data["host"] = "dev2.xiaomai.local"
+				return true, nil
+			},
+		},
+		report: &CloneReport{},
+	}
+
+	var created []*coreV1.ConfigMap
+	for _, name := range []string{"app-forum", "skip", "bad"} {
+		cm := &coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "dev1-xiaomai-server"},
+			Data:       map[string]string{"host": "dev1.xiaomai.local"},
+		}
+		n.clone("ConfigMap", cm, &cm.ObjectMeta, func() error {
+			created = append(created, cm)
+			return nil
+		}, nil)
+	}
+
+	if len(created) != 1 || created[0].Data["host"] != "dev2.xiaomai.local" || created[0].Namespace != "dev2-xiaomai-server" {
+		t.Fatalf("created = %+v", created)
+	}
+	if len(n.report.Cloned) != 1 || len(n.report.Skipped) != 1 || len(n.report.Failed) != 1 {
+		t.Fatalf("report = %+v", n.report)
+	}
+	if n.report.Err() == nil {
+		t.Fatal("expected aggregated error")
+	}
+}
+
+type fakeServiceMonitors struct {
+	montiroingV1.ServiceMonitorInterface
+	namespace string
+	created   map[string]*monitoringV1.ServiceMonitor
+}
+
+func (f *fakeServiceMonitors) ServiceMonitors(namespace string) montiroingV1.ServiceMonitorInterface {
+	return &fakeServiceMonitors{namespace: namespace, created: f.created}
+}
+
+func (f *fakeServiceMonitors) List(ctx context.Context, opts metav1.ListOptions) (*monitoringV1.ServiceMonitorList, error) {
+	return &monitoringV1.ServiceMonitorList{Items: []*monitoringV1.ServiceMonitor{{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-forum", Namespace: f.namespace, ResourceVersion: "7"},
+		Spec: monitoringV1.ServiceMonitorSpec{
+			NamespaceSelector: monitoringV1.NamespaceSelector{MatchNames: []string{f.namespace}},
+		},
+	}}}, nil
+}
+
+func (f *fakeServiceMonitors) Create(ctx context.Context, sm *monitoringV1.ServiceMonitor, opts metav1.CreateOptions) (*monitoringV1.ServiceMonitor, error) {
+	f.created[f.namespace+"/"+sm.Name] = sm
+	return sm, nil
+}
+
+func TestNamespaceCloner_ServiceMonitors(t *testing.T) {
+	n := &namespaceCloner{src: "dev1-xiaomai-server", dst: "dev2-xiaomai-server", report: &CloneReport{}}
+	// 没有传入ServiceMonitors时跳过
+	if err := n.serviceMonitors(context.Background()); err != nil || len(n.report.Cloned) != 0 {
+		t.Fatalf("err = %v, report = %+v", err, n.report)
+	}
+
+	fake := &fakeServiceMonitors{created: make(map[string]*monitoringV1.ServiceMonitor)}
+	n.opts.ServiceMonitors = fake
+	if err := n.serviceMonitors(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	sm := fake.created["dev2-xiaomai-server/app-forum"]
+	if sm == nil || sm.ResourceVersion != "" || sm.Spec.NamespaceSelector.MatchNames[0] != "dev2-xiaomai-server" {
+		t.Fatalf("created = %+v", fake.created)
+	}
+}
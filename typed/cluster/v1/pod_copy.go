@@ -0,0 +1,462 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"k8s.io/klog"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 复制本地文件或目录到容器中,destPath为容器中的目标路径.
+// 优先使用tar传输,容器中没有tar时回退到sh和cat逐个复制文件.
+// 返回传输的文件字节数
+func (p *pods) CopyToPod(ctx context.Context, podName, containerName, srcPath, destPath string) (int64, error) {
+	if _, err := os.Stat(srcPath); err != nil {
+		return 0, err
+	}
+	destPath = path.Clean(destPath)
+
+	hasTar, err := p.commandExists(ctx, podName, containerName, "tar")
+	if err != nil {
+		return 0, err
+	}
+	if !hasTar {
+		return p.copyToPodByCat(ctx, podName, containerName, srcPath, destPath)
+	}
+
+	type result struct {
+		n   int64
+		err error
+	}
+	done := make(chan result, 1)
+	reader, writer := io.Pipe()
+	go func() {
+		n, err := writeTar(writer, srcPath, path.Base(destPath))
+		writer.CloseWithError(err)
+		done <- result{n: n, err: err}
+	}()
+
+	destDir := path.Dir(destPath)
+	var stderr bytes.Buffer
+	_, err = p.Exec(ctx, podName, ExecOptions{
+		Container: containerName,
+		Command:   []string{"sh", "-c", fmt.Sprintf("mkdir -p %[1]s && tar -xf - -C %[1]s", shellQuote(destDir))},
+		Stdin:     reader,
+		Stdout:    ioutil.Discard,
+		Stderr:    &stderr,
+	})
+	// 远端提前退出时结束本地的打包
+	reader.Close()
+	res := <-done
+	if res.err != nil && res.err != io.ErrClosedPipe {
+		return 0, fmt.Errorf("tar %s err: %v", srcPath, res.err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("copy %s to pod %s err: %v %s", srcPath, podName, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return res.n, nil
+}
+
+// 复制容器中的文件或目录到本地,destPath为本地的目标路径.
+// 容器中没有tar时只支持复制单个文件.返回传输的文件字节数
+func (p *pods) CopyFromPod(ctx context.Context, podName, containerName, srcPath, destPath string) (int64, error) {
+	srcPath = path.Clean(srcPath)
+
+	hasTar, err := p.commandExists(ctx, podName, containerName, "tar")
+	if err != nil {
+		return 0, err
+	}
+	if !hasTar {
+		return p.copyFromPodByCat(ctx, podName, containerName, srcPath, destPath)
+	}
+
+	type result struct {
+		n   int64
+		err error
+	}
+	done := make(chan result, 1)
+	reader, writer := io.Pipe()
+	srcName := path.Base(srcPath)
+	go func() {
+		n, err := extractTar(reader, destPath, srcName)
+		if err != nil {
+			// 解包失败时中断远端的输出
+			reader.CloseWithError(err)
+		} else {
+			// 丢弃tar结尾的填充数据
+			io.Copy(ioutil.Discard, reader)
+		}
+		done <- result{n: n, err: err}
+	}()
+
+	var stderr bytes.Buffer
+	_, err = p.Exec(ctx, podName, ExecOptions{
+		Container: containerName,
+		Command:   []string{"tar", "-cf", "-", "-C", path.Dir(srcPath), srcName},
+		Stdout:    writer,
+		Stderr:    &stderr,
+	})
+	writer.CloseWithError(err)
+	res := <-done
+	if res.err != nil {
+		// 远端tar失败时解包通常也会失败,stderr中才是真正的原因
+		if err != nil {
+			return 0, fmt.Errorf("extract %s from pod %s err: %v, tar err: %v %s", srcPath, podName, res.err, err, strings.TrimSpace(stderr.String()))
+		}
+		return 0, fmt.Errorf("extract %s from pod %s err: %v %s", srcPath, podName, res.err, strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("copy %s from pod %s err: %v %s", srcPath, podName, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return res.n, nil
+}
+
+func (p *pods) commandExists(ctx context.Context, podName, containerName, command string) (bool, error) {
+	code, err := p.Exec(ctx, podName, ExecOptions{
+		Container: containerName,
+		Command:   []string{"sh", "-c", "command -v " + shellQuote(command)},
+		Stdout:    ioutil.Discard,
+	})
+	if code > 0 {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// 容器中没有tar时,逐个创建目录和文件
+func (p *pods) copyToPodByCat(ctx context.Context, podName, containerName, srcPath, destPath string) (int64, error) {
+	var written int64
+	err := filepath.Walk(srcPath, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcPath, file)
+		if err != nil {
+			return err
+		}
+		target := path.Join(destPath, filepath.ToSlash(rel))
+
+		var stderr bytes.Buffer
+		opts := ExecOptions{
+			Container: containerName,
+			Stdout:    ioutil.Discard,
+			Stderr:    &stderr,
+		}
+		switch {
+		case info.IsDir():
+			opts.Command = []string{"sh", "-c", fmt.Sprintf("mkdir -p %s", shellQuote(target))}
+		case info.Mode().IsRegular():
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			counter := &countingReader{r: f}
+			opts.Stdin = counter
+			opts.Command = []string{"sh", "-c", fmt.Sprintf("mkdir -p %[1]s && cat > %[2]s && chmod %[3]o %[2]s",
+				shellQuote(path.Dir(target)), shellQuote(target), info.Mode().Perm())}
+			defer func() {
+				written += counter.n
+			}()
+		default:
+			klog.Warningf("skipping %s: unsupported file mode %s", file, info.Mode())
+			return nil
+		}
+
+		if _, err := p.Exec(ctx, podName, opts); err != nil {
+			return fmt.Errorf("copy %s to pod %s err: %v %s", file, podName, err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	})
+
+	return written, err
+}
+
+// 容器中没有tar时,通过cat复制单个文件
+func (p *pods) copyFromPodByCat(ctx context.Context, podName, containerName, srcPath, destPath string) (int64, error) {
+	var stdout bytes.Buffer
+	script := fmt.Sprintf("if [ -d %[1]s ]; then echo dir; else stat -c %%a %[1]s 2>/dev/null || echo 644; fi", shellQuote(srcPath))
+	_, err := p.Exec(ctx, podName, ExecOptions{
+		Container: containerName,
+		Command:   []string{"sh", "-c", script},
+		Stdout:    &stdout,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	out := strings.TrimSpace(stdout.String())
+	if out == "dir" {
+		return 0, fmt.Errorf("tar is required to copy directory %s from pod %s", srcPath, podName)
+	}
+	mode, err := strconv.ParseUint(out, 8, 32)
+	if err != nil {
+		mode = 0644
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(mode))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var stderr bytes.Buffer
+	counter := &countingWriter{w: f}
+	_, err = p.Exec(ctx, podName, ExecOptions{
+		Container: containerName,
+		Command:   []string{"cat", srcPath},
+		Stdout:    counter,
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("copy %s from pod %s err: %v %s", srcPath, podName, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return counter.n, os.Chmod(destPath, os.FileMode(mode))
+}
+
+// writeTar 将srcPath打包写入w,包内的根路径为name,返回写入的文件字节数
+func writeTar(w io.Writer, srcPath, name string) (int64, error) {
+	var written int64
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(srcPath, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcPath, file)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(name, filepath.ToSlash(rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		n, err := io.Copy(tw, f)
+		written += n
+		return err
+	})
+	if err != nil {
+		return written, err
+	}
+
+	return written, tw.Close()
+}
+
+// extractTar 将包内name下的内容解压到destPath,拒绝解压到destPath之外的路径,
+// 也拒绝通过已经解压的符号链接写入.返回写入的文件字节数
+func extractTar(r io.Reader, destPath, name string) (int64, error) {
+	var written int64
+	// 目录的权限在解压完所有内容之后再设置,避免只读目录导致无法写入其中的文件
+	dirModes := make(map[string]os.FileMode)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return written, err
+		}
+
+		target, err := archiveTarget(destPath, name, hdr.Name)
+		if err != nil {
+			return written, err
+		}
+		if err := noSymlinkWithin(destPath, target); err != nil {
+			return written, err
+		}
+
+		mode := hdr.FileInfo().Mode().Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return written, err
+			}
+			dirModes[target] = mode
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return written, err
+			}
+			n, err := writeFile(target, tr, mode)
+			written += n
+			if err != nil {
+				return written, err
+			}
+		case tar.TypeSymlink:
+			if !linkWithin(destPath, target, hdr.Linkname) {
+				klog.Warningf("skipping symlink %s -> %s: points outside %s", hdr.Name, hdr.Linkname, destPath)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return written, err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return written, err
+			}
+		default:
+			klog.Warningf("skipping %s: unsupported tar entry type %c", hdr.Name, hdr.Typeflag)
+		}
+	}
+
+	for dir, mode := range dirModes {
+		if err := os.Chmod(dir, mode); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// noSymlinkWithin 检查destPath到target之间已经存在的路径都不是符号链接,
+// 否则通过符号链接链可以把文件写到destPath之外
+func noSymlinkWithin(destPath, target string) error {
+	rel, err := filepath.Rel(destPath, target)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+
+	current := destPath
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("illegal path in archive: %s is a symlink", current)
+		}
+	}
+	return nil
+}
+
+// archiveTarget 返回包内文件在本地的路径
+func archiveTarget(destPath, name, entry string) (string, error) {
+	entry = path.Clean(entry)
+	if path.IsAbs(entry) {
+		return "", fmt.Errorf("illegal absolute path in archive: %s", entry)
+	}
+
+	var rel string
+	switch {
+	case entry == name:
+	case strings.HasPrefix(entry, name+"/"):
+		rel = strings.TrimPrefix(entry, name+"/")
+	default:
+		return "", fmt.Errorf("unexpected path in archive: %s", entry)
+	}
+
+	target := filepath.Join(destPath, filepath.FromSlash(rel))
+	if !within(destPath, target) {
+		return "", fmt.Errorf("illegal path in archive: %s", entry)
+	}
+
+	return target, nil
+}
+
+func linkWithin(destPath, target, link string) bool {
+	if filepath.IsAbs(link) {
+		return false
+	}
+
+	return within(destPath, filepath.Join(filepath.Dir(target), link))
+}
+
+func within(dir, file string) bool {
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func writeFile(file string, r io.Reader, mode os.FileMode) (int64, error) {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return n, err
+	}
+
+	return n, os.Chmod(file, mode)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
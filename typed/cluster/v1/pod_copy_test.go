@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTarExtractTar(t *testing.T) {
+	src, err := ioutil.TempDir("", "copy-src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	dest, err := ioutil.TempDir("", "copy-dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	if err := os.MkdirAll(filepath.Join(src, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "bin", "dump.sh"), []byte("#!/bin/sh\necho dump\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "app.conf"), []byte("port=8080\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	written, err := writeTar(&buf, src, "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 30 {
+		t.Fatalf("written %d bytes, want 30", written)
+	}
+
+	target := filepath.Join(dest, "conf")
+	extracted, err := extractTar(&buf, target, "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extracted != written {
+		t.Fatalf("extracted %d bytes, want %d", extracted, written)
+	}
+
+	info, err := os.Stat(filepath.Join(target, "bin", "dump.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Fatalf("mode %v, want 0755", info.Mode().Perm())
+	}
+	info, err = os.Stat(filepath.Join(target, "app.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("mode %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestExtractTar_PathTraversal(t *testing.T) {
+	dest, err := ioutil.TempDir("", "copy-dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	for _, name := range []string{"conf/../../evil", "/etc/passwd", "other/file"} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 4, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte("evil"))
+		tw.Close()
+
+		if _, err := extractTar(&buf, filepath.Join(dest, "conf"), "conf"); err == nil {
+			t.Fatalf("expected %s to be rejected", name)
+		}
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "conf/link", Linkname: "../../etc/passwd", Typeflag: tar.TypeSymlink})
+	tw.Close()
+	if _, err := extractTar(&buf, filepath.Join(dest, "conf"), "conf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(dest, "conf", "link")); !os.IsNotExist(err) {
+		t.Fatalf("symlink outside destination should be skipped")
+	}
+}
+
+func TestExtractTar_SymlinkChain(t *testing.T) {
+	root, err := ioutil.TempDir("", "copy-root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dest := filepath.Join(root, "dest")
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "conf/d/", Mode: 0755, Typeflag: tar.TypeDir})
+	tw.WriteHeader(&tar.Header{Name: "conf/d/l", Linkname: "..", Typeflag: tar.TypeSymlink})
+	tw.WriteHeader(&tar.Header{Name: "conf/d/l/m", Linkname: "..", Typeflag: tar.TypeSymlink})
+	tw.WriteHeader(&tar.Header{Name: "conf/d/l/m/pwned", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+	tw.Write([]byte("evil"))
+	tw.Close()
+
+	if _, err := extractTar(&buf, dest, "conf"); err == nil {
+		t.Fatal("expected writes through symlinks to be rejected")
+	}
+	for _, file := range []string{filepath.Join(root, "pwned"), filepath.Join(dest, "pwned")} {
+		if _, err := os.Lstat(file); !os.IsNotExist(err) {
+			t.Fatalf("%s should not exist", file)
+		}
+	}
+}
+
+func TestExtractTar_ReadOnlyDir(t *testing.T) {
+	dest, err := ioutil.TempDir("", "copy-dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	target := filepath.Join(dest, "conf")
+	defer os.Chmod(filepath.Join(target, "ro"), 0755)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "conf/ro/", Mode: 0555, Typeflag: tar.TypeDir})
+	tw.WriteHeader(&tar.Header{Name: "conf/ro/app.conf", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+	tw.Write([]byte("port"))
+	tw.Close()
+
+	if _, err := extractTar(&buf, target, "conf"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(target, "ro"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0555 {
+		t.Fatalf("mode %v, want 0555", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(target, "ro", "app.conf")); err != nil {
+		t.Fatal(err)
+	}
+}
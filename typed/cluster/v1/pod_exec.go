@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	utilexec "k8s.io/client-go/util/exec"
+	"net/http"
+	"sync"
+)
+
+// ExecOptions 在容器中执行命令的参数
+type ExecOptions struct {
+	Container string
+	Command   []string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	// 开启TTY时stderr会合并到stdout中,Stderr将被忽略.
+	// 没有开启TTY时总是请求stderr,为空时丢弃
+	Stderr io.Writer
+	TTY    bool
+	// 终端大小变化队列,只在TTY模式下生效
+	TerminalSizeQueue remotecommand.TerminalSizeQueue
+}
+
+// 在容器中执行命令,返回命令的退出码.
+// 命令以非0退出时同时返回退出码和错误,其他错误的退出码为-1.
+// ctx结束时会关闭与apiserver之间的连接
+func (p *pods) Exec(ctx context.Context, podName string, opts ExecOptions) (int, error) {
+	_, err := p.Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return -1, fmt.Errorf("get pod %s err : %v", podName, err)
+	}
+
+	streams, err := execStreams(opts)
+	if err != nil {
+		return -1, err
+	}
+
+	req := p.client.CoreV1().RESTClient().Post().Resource("pods").Namespace(p.ns).Name(podName).SubResource("exec").VersionedParams(&v1.PodExecOptions{
+		Stdin:     streams.Stdin != nil,
+		Stdout:    streams.Stdout != nil,
+		Stderr:    streams.Stderr != nil,
+		TTY:       streams.Tty,
+		Container: opts.Container,
+		Command:   opts.Command,
+	}, scheme.ParameterCodec)
+
+	transport, upgrader, err := spdy.RoundTripperFor(p.restConfig)
+	if err != nil {
+		return -1, fmt.Errorf("error while creating round tripper: %v", err)
+	}
+
+	conn := &cancelableUpgrader{Upgrader: upgrader}
+	exec, err := remotecommand.NewSPDYExecutorForTransports(transport, conn, "POST", req.URL())
+	if err != nil {
+		return -1, fmt.Errorf("error while creating executor: %v", err)
+	}
+
+	// remotecommand不支持ctx,ctx结束时直接关闭底层连接来中断Stream
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = exec.Stream(streams)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+
+		if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.Exited() {
+			return exitErr.ExitStatus(), fmt.Errorf("command terminated with exit code %d", exitErr.ExitStatus())
+		}
+
+		return -1, fmt.Errorf("error in Stream: %v", err)
+	}
+
+	return 0, nil
+}
+
+// execStreams 返回Stream使用的输入输出.apiserver要求至少请求一个流,
+// 没有开启TTY时总是请求stderr,这样不传任何输入输出也能拿到退出码
+func execStreams(opts ExecOptions) (remotecommand.StreamOptions, error) {
+	streams := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Tty:    opts.TTY,
+	}
+	if opts.TTY {
+		if opts.Stdin == nil && opts.Stdout == nil {
+			return streams, fmt.Errorf("stdin or stdout is required when tty is enabled")
+		}
+		streams.TerminalSizeQueue = opts.TerminalSizeQueue
+		return streams, nil
+	}
+
+	streams.Stderr = opts.Stderr
+	if streams.Stderr == nil {
+		streams.Stderr = ioutil.Discard
+	}
+	return streams, nil
+}
+
+// cancelableUpgrader 记录升级后的连接,以便在ctx结束时关闭
+type cancelableUpgrader struct {
+	spdy.Upgrader
+
+	mu     sync.Mutex
+	conn   httpstream.Connection
+	closed bool
+}
+
+func (c *cancelableUpgrader) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	conn, err := c.Upgrader.NewConnection(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		conn.Close()
+		return nil, fmt.Errorf("exec canceled")
+	}
+	c.conn = conn
+
+	return conn, nil
+}
+
+func (c *cancelableUpgrader) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io/ioutil"
 	"k8s.io/client-go/tools/clientcmd"
 	"testing"
 )
@@ -25,18 +24,46 @@ func TestPods_Exec(t *testing.T) {
 	commands := []string{"/bin/bash", "-c"}
 	commands = append(commands, fmt.Sprintf("ls -l"))
 
-	var stdout bytes.Buffer
+	var stdout, stderr bytes.Buffer
 
-	stderr, err := client.Pods(namespace).Exec(ctx, pod, container, commands, nil, &stdout)
+	code, err := client.Pods(namespace).Exec(ctx, pod, ExecOptions{
+		Container: container,
+		Command:   commands,
+		Stdout:    &stdout,
+		Stderr:    &stderr,
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	fmt.Println(stderr)
+	fmt.Println(code)
+	fmt.Println(stderr.String())
 	fmt.Println(stdout.String())
 
 }
 
+func TestPods_ExecExitCode(t *testing.T) {
+	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ := NewForConfig(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	namespace := "dev-xiaomai-server"
+	pod := "dev-app-gateway-latest-76ddb96f4c-nbkrr"
+	container := "app-gateway"
+
+	code, err := client.Pods(namespace).Exec(ctx, pod, ExecOptions{
+		Container: container,
+		Command:   []string{"sh", "-c", "exit 3"},
+	})
+	if code != 3 {
+		t.Fatalf("exit code %d, err: %v", code, err)
+	}
+}
+
 func TestPods_CopyToPod(t *testing.T) {
 	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
 	if err != nil {
@@ -49,14 +76,48 @@ func TestPods_CopyToPod(t *testing.T) {
 	pod := "dev-app-gateway-latest-76ddb96f4c-nbkrr"
 	container := "app-gateway"
 
-	testFile, err := ioutil.ReadFile("D:/tmp/awesomeProject")
+	written, err := client.Pods(namespace).CopyToPod(ctx, pod, container, "D:/tmp/awesomeProject", "/tmp/main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Logf("written : %d", written)
+}
+
+func TestPods_CopyFromPod(t *testing.T) {
+	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
 	if err != nil {
 		t.Fatal(err)
 	}
-	stderr, err := client.Pods(namespace).CopyToPod(ctx, pod, container, bytes.NewReader(testFile), "/tmp/main")
+	client, _ := NewForConfig(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	namespace := "dev-xiaomai-server"
+	pod := "dev-app-gateway-latest-76ddb96f4c-nbkrr"
+	container := "app-gateway"
+
+	written, err := client.Pods(namespace).CopyFromPod(ctx, pod, container, "/etc", "D:/tmp/etc")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Logf("stderr : %s", string(stderr))
+	t.Logf("written : %d", written)
+}
+
+func TestExecStreams(t *testing.T) {
+	// 不传输入输出时也要请求stderr,否则apiserver拒绝请求
+	streams, err := execStreams(ExecOptions{Command: []string{"sh", "-c", "exit 3"}})
+	if err != nil || streams.Stderr == nil || streams.Stdin != nil || streams.Stdout != nil {
+		t.Fatalf("streams = %+v, err = %v", streams, err)
+	}
+
+	var stdout bytes.Buffer
+	streams, err = execStreams(ExecOptions{Stdout: &stdout, Stderr: &stdout, TTY: true})
+	if err != nil || streams.Stderr != nil || !streams.Tty {
+		t.Fatalf("tty streams = %+v, err = %v", streams, err)
+	}
+
+	if _, err := execStreams(ExecOptions{TTY: true}); err == nil {
+		t.Fatal("expected error for tty without stdin and stdout")
+	}
 }
@@ -0,0 +1,252 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"net/http"
+)
+
+// PodPredicate 判断pod是否达到期望的状态,pod为nil表示pod不存在.
+// 返回错误时立即结束等待
+type PodPredicate func(pod *v1.Pod) (bool, error)
+
+// 处于这些状态的容器不会自行恢复
+var terminalWaitingReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+}
+
+// pod处于Running
+func PodRunning(pod *v1.Pod) (bool, error) {
+	if pod == nil {
+		return false, nil
+	}
+	if err := podFailure(pod); err != nil {
+		return false, err
+	}
+
+	return pod.Status.Phase == v1.PodRunning, nil
+}
+
+// pod的Ready condition为True
+func PodReady(pod *v1.Pod) (bool, error) {
+	if pod == nil {
+		return false, nil
+	}
+	if err := podFailure(pod); err != nil {
+		return false, err
+	}
+
+	return podConditionTrue(pod, v1.PodReady), nil
+}
+
+// pod的ContainersReady condition为True
+func PodContainersReady(pod *v1.Pod) (bool, error) {
+	if pod == nil {
+		return false, nil
+	}
+	if err := podFailure(pod); err != nil {
+		return false, err
+	}
+
+	return podConditionTrue(pod, v1.ContainersReady), nil
+}
+
+// pod运行成功结束,运行失败时返回错误
+func PodCompleted(pod *v1.Pod) (bool, error) {
+	if pod == nil {
+		return false, nil
+	}
+	if err := podFailure(pod); err != nil {
+		return false, err
+	}
+
+	return pod.Status.Phase == v1.PodSucceeded, nil
+}
+
+// pod已经被删除
+func PodDeleted(pod *v1.Pod) (bool, error) {
+	return pod == nil, nil
+}
+
+// 等待pod满足predicate,返回最后一次观察到的pod
+func (p *pods) WaitFor(ctx context.Context, name string, predicate PodPredicate) (*v1.Pod, error) {
+	var pod *v1.Pod
+	opts := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	}
+
+	err := p.watchUntil(ctx, opts, func(items []v1.Pod) (bool, error) {
+		pod = nil
+		if len(items) > 0 {
+			pod = &items[0]
+		}
+		return predicate(pod)
+	}, func(event watch.Event) (bool, error) {
+		pod = event.Object.(*v1.Pod)
+		if event.Type == watch.Deleted {
+			pod = nil
+		}
+		return predicate(pod)
+	})
+
+	return pod, err
+}
+
+// 等待selector选中的pod中至少minReady个满足predicate,minReady<=0时要求全部满足.
+// 返回满足predicate的pod
+func (p *pods) WaitForSelector(ctx context.Context, selector string, minReady int, predicate PodPredicate) ([]v1.Pod, error) {
+	current := make(map[string]*v1.Pod)
+	var matched []v1.Pod
+
+	check := func() (bool, error) {
+		var done bool
+		var err error
+		matched, done, err = selectorSatisfied(current, minReady, predicate)
+		return done, err
+	}
+
+	err := p.watchUntil(ctx, metav1.ListOptions{LabelSelector: selector}, func(items []v1.Pod) (bool, error) {
+		current = make(map[string]*v1.Pod, len(items))
+		for i := range items {
+			current[items[i].Name] = &items[i]
+		}
+		return check()
+	}, func(event watch.Event) (bool, error) {
+		pod := event.Object.(*v1.Pod)
+		if event.Type == watch.Deleted {
+			delete(current, pod.Name)
+		} else {
+			current[pod.Name] = pod
+		}
+		return check()
+	})
+
+	return matched, err
+}
+
+// watchUntil 先list再从list的resourceVersion开始watch,watch断开后从最后的
+// resourceVersion继续,resourceVersion过期时重新list
+func (p *pods) watchUntil(ctx context.Context, opts metav1.ListOptions, onList func(items []v1.Pod) (bool, error), onEvent func(event watch.Event) (bool, error)) error {
+	var resourceVersion string
+	for {
+		if resourceVersion == "" {
+			list, err := p.List(ctx, opts)
+			if err != nil {
+				return err
+			}
+			done, err := onList(list.Items)
+			if err != nil || done {
+				return err
+			}
+			resourceVersion = list.ResourceVersion
+		}
+
+		watchOpts := opts
+		watchOpts.ResourceVersion = resourceVersion
+		w, err := p.Watch(ctx, watchOpts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+				resourceVersion = ""
+				continue
+			}
+			return err
+		}
+
+		var done bool
+		resourceVersion, done, err = p.consumeWatch(ctx, w, resourceVersion, onEvent)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// consumeWatch 处理watch事件直到条件满足或watch断开,返回最后的resourceVersion,
+// resourceVersion过期时返回空字符串
+func (p *pods) consumeWatch(ctx context.Context, w watch.Interface, resourceVersion string, onEvent func(event watch.Event) (bool, error)) (string, bool, error) {
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, false, ctx.Err()
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion, false, nil
+			}
+
+			switch event.Type {
+			case watch.Added, watch.Modified, watch.Deleted:
+				pod, ok := event.Object.(*v1.Pod)
+				if !ok {
+					continue
+				}
+				resourceVersion = pod.ResourceVersion
+				done, err := onEvent(event)
+				if err != nil || done {
+					return resourceVersion, done, err
+				}
+			case watch.Error:
+				status, ok := event.Object.(*metav1.Status)
+				if ok && (status.Code == http.StatusGone || status.Reason == metav1.StatusReasonExpired) {
+					return "", false, nil
+				}
+				return resourceVersion, false, apierrors.FromObject(event.Object)
+			}
+		}
+	}
+}
+
+// selectorSatisfied 返回满足predicate的pod,以及是否已经有minReady个满足.
+// minReady<=0时要求至少有一个pod并且全部满足,避免pod创建之前就结束等待
+func selectorSatisfied(current map[string]*v1.Pod, minReady int, predicate PodPredicate) ([]v1.Pod, bool, error) {
+	var matched []v1.Pod
+	for _, pod := range current {
+		ok, err := predicate(pod)
+		if err != nil {
+			return matched, false, err
+		}
+		if ok {
+			matched = append(matched, *pod)
+		}
+	}
+
+	if minReady <= 0 {
+		return matched, len(current) > 0 && len(matched) == len(current), nil
+	}
+	return matched, len(matched) >= minReady, nil
+}
+
+func podConditionTrue(pod *v1.Pod, conditionType v1.PodConditionType) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == conditionType {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podFailure 检查pod是否处于无法自行恢复的状态
+func podFailure(pod *v1.Pod) error {
+	if pod.Status.Phase == v1.PodFailed {
+		return fmt.Errorf("pod %s failed: %s %s", pod.Name, pod.Status.Reason, pod.Status.Message)
+	}
+
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Waiting != nil && terminalWaitingReasons[status.State.Waiting.Reason] {
+			return fmt.Errorf("container %s of pod %s is in %s: %s", status.Name, pod.Name, status.State.Waiting.Reason, status.State.Waiting.Message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"testing"
+	"time"
+)
+
+func TestPodPredicates(t *testing.T) {
+	ready := &v1.Pod{Status: v1.PodStatus{
+		Phase:      v1.PodRunning,
+		Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+	}}
+	if ok, err := PodReady(ready); !ok || err != nil {
+		t.Fatalf("PodReady = %v, %v", ok, err)
+	}
+	if ok, _ := PodContainersReady(ready); ok {
+		t.Fatal("PodContainersReady should be false without ContainersReady condition")
+	}
+	if ok, _ := PodReady(nil); ok {
+		t.Fatal("PodReady should be false for deleted pod")
+	}
+	if ok, _ := PodDeleted(nil); !ok {
+		t.Fatal("PodDeleted should be true for deleted pod")
+	}
+
+	crashLoop := &v1.Pod{Status: v1.PodStatus{
+		Phase: v1.PodRunning,
+		ContainerStatuses: []v1.ContainerStatus{{
+			Name:  "app",
+			State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		}},
+	}}
+	if _, err := PodReady(crashLoop); err == nil {
+		t.Fatal("PodReady should fail on CrashLoopBackOff")
+	}
+
+	failed := &v1.Pod{Status: v1.PodStatus{Phase: v1.PodFailed}}
+	if _, err := PodCompleted(failed); err == nil {
+		t.Fatal("PodCompleted should fail on failed pod")
+	}
+	succeeded := &v1.Pod{Status: v1.PodStatus{Phase: v1.PodSucceeded}}
+	if ok, err := PodCompleted(succeeded); !ok || err != nil {
+		t.Fatalf("PodCompleted = %v, %v", ok, err)
+	}
+}
+
+func TestSelectorSatisfied(t *testing.T) {
+	ready := &v1.Pod{Status: v1.PodStatus{
+		Phase:      v1.PodRunning,
+		Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+	}}
+	pending := &v1.Pod{Status: v1.PodStatus{Phase: v1.PodPending}}
+
+	// 还没有pod时不能结束等待
+	if _, done, err := selectorSatisfied(map[string]*v1.Pod{}, 0, PodReady); done || err != nil {
+		t.Fatalf("empty selector done = %v, %v", done, err)
+	}
+
+	current := map[string]*v1.Pod{"a": ready, "b": pending}
+	if _, done, _ := selectorSatisfied(current, 0, PodReady); done {
+		t.Fatal("all pods should be required when minReady <= 0")
+	}
+	if matched, done, _ := selectorSatisfied(current, 1, PodReady); !done || len(matched) != 1 {
+		t.Fatalf("minReady 1: done = %v, matched = %d", done, len(matched))
+	}
+
+	current["b"] = ready
+	if matched, done, _ := selectorSatisfied(current, 0, PodReady); !done || len(matched) != 2 {
+		t.Fatalf("all ready: done = %v, matched = %d", done, len(matched))
+	}
+}
+
+func TestPods_WaitForSelector(t *testing.T) {
+	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ := NewForConfig(c)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	pods, err := client.Pods("dev-xiaomai-server").WaitForSelector(ctx, "app=app-gateway", 1, PodReady)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pod := range pods {
+		t.Logf("ready pod : %s", pod.Name)
+	}
+}
@@ -0,0 +1,297 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"sort"
+	"strings"
+	"time"
+)
+
+type WorkloadKind string
+
+const (
+	KindDeployment  WorkloadKind = "Deployment"
+	KindStatefulSet WorkloadKind = "StatefulSet"
+	KindDaemonSet   WorkloadKind = "DaemonSet"
+	KindCronJob     WorkloadKind = "CronJob"
+
+	rolloutPollInterval = 2 * time.Second
+)
+
+type WorkloadsGetter interface {
+	Workloads(namespace string) WorkloadsInterface
+}
+
+type WorkloadsInterface interface {
+	SetImage(ctx context.Context, kind WorkloadKind, name string, images map[string]string, opts SetImageOptions) error
+	WaitForRollout(ctx context.Context, kind WorkloadKind, name string) error
+}
+
+type SetImageOptions struct {
+	// 记录到kubernetes.io/change-cause注解,为空时自动生成
+	ChangeCause string
+	// 等待滚动更新完成,CronJob没有滚动更新,会被忽略
+	Wait bool
+}
+
+type workloads struct {
+	client *kubernetes.Clientset
+	ns     string
+}
+
+func newWorkloads(c *kubernetes.Clientset, namespace string) *workloads {
+	return &workloads{
+		client: c,
+		ns:     namespace,
+	}
+}
+
+// 修改工作负载中容器的镜像,images的key为容器名,支持init容器.
+// 容器名不存在时返回错误,修改在一次patch中完成并带上resourceVersion,
+// 对象在此期间被修改时返回冲突错误
+func (w *workloads) SetImage(ctx context.Context, kind WorkloadKind, name string, images map[string]string, opts SetImageOptions) error {
+	meta, podSpec, err := w.podTemplate(ctx, kind, name)
+	if err != nil {
+		return err
+	}
+
+	containers, initContainers, err := imagePatches(podSpec, images)
+	if err != nil {
+		return fmt.Errorf("%s %s: %v", kind, name, err)
+	}
+
+	changeCause := opts.ChangeCause
+	if changeCause == "" {
+		changeCause = fmt.Sprintf("set image %s/%s %s", strings.ToLower(string(kind)), name, formatImages(images))
+	}
+
+	spec := map[string]interface{}{}
+	if len(containers) > 0 {
+		spec["containers"] = containers
+	}
+	if len(initContainers) > 0 {
+		spec["initContainers"] = initContainers
+	}
+	template := map[string]interface{}{"spec": spec}
+	if kind == KindCronJob {
+		template = map[string]interface{}{
+			"jobTemplate": map[string]interface{}{
+				"spec": map[string]interface{}{"template": template},
+			},
+		}
+	} else {
+		template = map[string]interface{}{"template": template}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": meta.ResourceVersion,
+			"annotations":     map[string]string{changeCauseAnnotation: changeCause},
+		},
+		"spec": template,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := w.patch(ctx, kind, name, data); err != nil {
+		return err
+	}
+
+	if opts.Wait && kind != KindCronJob {
+		return w.WaitForRollout(ctx, kind, name)
+	}
+	return nil
+}
+
+// 等待Deployment,StatefulSet或DaemonSet滚动更新完成
+func (w *workloads) WaitForRollout(ctx context.Context, kind WorkloadKind, name string) error {
+	return wait.PollImmediateUntil(rolloutPollInterval, func() (bool, error) {
+		switch kind {
+		case KindDeployment:
+			d, err := w.client.AppsV1().Deployments(w.ns).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			return deploymentRolledOut(d)
+		case KindStatefulSet:
+			s, err := w.client.AppsV1().StatefulSets(w.ns).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			return statefulSetRolledOut(s), nil
+		case KindDaemonSet:
+			d, err := w.client.AppsV1().DaemonSets(w.ns).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			return daemonSetRolledOut(d), nil
+		}
+		return false, fmt.Errorf("%s has no rollout status", kind)
+	}, ctx.Done())
+}
+
+// podTemplate 返回对象的metadata和pod模板
+func (w *workloads) podTemplate(ctx context.Context, kind WorkloadKind, name string) (*metav1.ObjectMeta, *coreV1.PodSpec, error) {
+	switch kind {
+	case KindDeployment:
+		d, err := w.client.AppsV1().Deployments(w.ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &d.ObjectMeta, &d.Spec.Template.Spec, nil
+	case KindStatefulSet:
+		s, err := w.client.AppsV1().StatefulSets(w.ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &s.ObjectMeta, &s.Spec.Template.Spec, nil
+	case KindDaemonSet:
+		d, err := w.client.AppsV1().DaemonSets(w.ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &d.ObjectMeta, &d.Spec.Template.Spec, nil
+	case KindCronJob:
+		c, err := w.client.BatchV1beta1().CronJobs(w.ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &c.ObjectMeta, &c.Spec.JobTemplate.Spec.Template.Spec, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported workload kind %s", kind)
+}
+
+func (w *workloads) patch(ctx context.Context, kind WorkloadKind, name string, data []byte) error {
+	var err error
+	switch kind {
+	case KindDeployment:
+		_, err = w.client.AppsV1().Deployments(w.ns).Patch(ctx, name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	case KindStatefulSet:
+		_, err = w.client.AppsV1().StatefulSets(w.ns).Patch(ctx, name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	case KindDaemonSet:
+		_, err = w.client.AppsV1().DaemonSets(w.ns).Patch(ctx, name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	case KindCronJob:
+		_, err = w.client.BatchV1beta1().CronJobs(w.ns).Patch(ctx, name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	default:
+		err = fmt.Errorf("unsupported workload kind %s", kind)
+	}
+	return err
+}
+
+// imagePatches 校验容器名并生成容器和init容器的patch
+func imagePatches(podSpec *coreV1.PodSpec, images map[string]string) ([]map[string]string, []map[string]string, error) {
+	found := make(map[string]bool, len(images))
+	var containers, initContainers []map[string]string
+	for _, c := range podSpec.Containers {
+		if image, ok := images[c.Name]; ok {
+			found[c.Name] = true
+			containers = append(containers, map[string]string{"name": c.Name, "image": image})
+		}
+	}
+	for _, c := range podSpec.InitContainers {
+		if image, ok := images[c.Name]; ok {
+			found[c.Name] = true
+			initContainers = append(initContainers, map[string]string{"name": c.Name, "image": image})
+		}
+	}
+
+	var unknown []string
+	for name := range images {
+		if !found[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, nil, fmt.Errorf("unknown containers %s", strings.Join(unknown, ","))
+	}
+
+	return containers, initContainers, nil
+}
+
+// setTemplateImages 直接修改pod模板中容器的镜像
+func setTemplateImages(template *coreV1.PodTemplateSpec, images map[string]string) error {
+	containers, initContainers, err := imagePatches(&template.Spec, images)
+	if err != nil {
+		return err
+	}
+	for _, patch := range containers {
+		for i := range template.Spec.Containers {
+			if template.Spec.Containers[i].Name == patch["name"] {
+				template.Spec.Containers[i].Image = patch["image"]
+			}
+		}
+	}
+	for _, patch := range initContainers {
+		for i := range template.Spec.InitContainers {
+			if template.Spec.InitContainers[i].Name == patch["name"] {
+				template.Spec.InitContainers[i].Image = patch["image"]
+			}
+		}
+	}
+	return nil
+}
+
+func formatImages(images map[string]string) string {
+	pairs := make([]string, 0, len(images))
+	for name, image := range images {
+		pairs = append(pairs, name+"="+image)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+func deploymentRolledOut(d *appsV1.Deployment) (bool, error) {
+	if d.Generation > d.Status.ObservedGeneration {
+		return false, nil
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsV1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return false, fmt.Errorf("deployment %s exceeded its progress deadline", d.Name)
+		}
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == replicas &&
+		d.Status.AvailableReplicas == replicas, nil
+}
+
+func statefulSetRolledOut(s *appsV1.StatefulSet) bool {
+	if s.Generation > s.Status.ObservedGeneration {
+		return false
+	}
+
+	// OnDelete策略需要手动删除pod才会更新
+	if s.Spec.UpdateStrategy.Type == appsV1.OnDeleteStatefulSetStrategyType {
+		return true
+	}
+
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	return s.Status.UpdateRevision == s.Status.CurrentRevision &&
+		s.Status.ReadyReplicas == replicas &&
+		s.Status.UpdatedReplicas == replicas
+}
+
+func daemonSetRolledOut(d *appsV1.DaemonSet) bool {
+	if d.Generation > d.Status.ObservedGeneration {
+		return false
+	}
+	return d.Status.UpdatedNumberScheduled == d.Status.DesiredNumberScheduled &&
+		d.Status.NumberAvailable == d.Status.DesiredNumberScheduled
+}
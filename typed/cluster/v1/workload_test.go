@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"context"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"testing"
+)
+
+func TestImagePatches(t *testing.T) {
+	podSpec := &coreV1.PodSpec{
+		InitContainers: []coreV1.Container{{Name: "migrate", Image: "app-migrate:v1"}},
+		Containers:     []coreV1.Container{{Name: "app", Image: "app:v1"}, {Name: "sidecar", Image: "envoy:v1"}},
+	}
+
+	containers, initContainers, err := imagePatches(podSpec, map[string]string{"app": "app:v2", "migrate": "app-migrate:v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 1 || containers[0]["image"] != "app:v2" {
+		t.Fatalf("unexpected containers patch: %v", containers)
+	}
+	if len(initContainers) != 1 || initContainers[0]["image"] != "app-migrate:v2" {
+		t.Fatalf("unexpected init containers patch: %v", initContainers)
+	}
+
+	if _, _, err := imagePatches(podSpec, map[string]string{"ap": "app:v2"}); err == nil {
+		t.Fatal("expected error for unknown container")
+	}
+}
+
+func TestWorkloads_SetImage(t *testing.T) {
+	c, err := clientcmd.BuildConfigFromKubeconfigGetter("", KubeConfigGetter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := NewForConfig(c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = client.Workloads("dev1-xiaomai-server").SetImage(ctx, KindDeployment, "dev1-app-forum-latest", map[string]string{
+		"app-forum": "registry.local/app-forum:latest",
+	}, SetImageOptions{Wait: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
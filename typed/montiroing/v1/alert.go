@@ -0,0 +1,51 @@
+package v1
+
+import (
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"time"
+)
+
+// WebhookMessage Alertmanager webhook发送的消息,version为4
+type WebhookMessage struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []WebhookAlert    `json:"alerts"`
+}
+
+type WebhookAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// 返回消息中所有告警的label,可以传给Kubernetes().CorrelateAlerts
+func (m *WebhookMessage) AlertLabels() []map[string]string {
+	labels := make([]map[string]string, 0, len(m.Alerts))
+	for _, a := range m.Alerts {
+		labels = append(labels, a.Labels)
+	}
+	return labels
+}
+
+// 返回Prometheus API中告警的label,可以传给Kubernetes().CorrelateAlerts
+func AlertLabels(alerts []promv1.Alert) []map[string]string {
+	result := make([]map[string]string, 0, len(alerts))
+	for _, a := range alerts {
+		labels := make(map[string]string, len(a.Labels))
+		for k, v := range a.Labels {
+			labels[string(k)] = string(v)
+		}
+		result = append(result, labels)
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/coreos/prometheus-operator/pkg/client/versioned"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// operator挂载到Alertmanager中的配置文件
+	alertmanagerConfigKey = "alertmanager.yaml"
+)
+
+type AlertmanagersGetter interface {
+	Alertmanagers(namespace string) AlertmanagerInterface
+}
+
+type AlertmanagerInterface interface {
+	Create(ctx context.Context, alertmanager *v1.Alertmanager, opts metav1.CreateOptions) (*v1.Alertmanager, error)
+	Update(ctx context.Context, alertmanager *v1.Alertmanager, opts metav1.UpdateOptions) (*v1.Alertmanager, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	//DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Alertmanager, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.AlertmanagerList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan AlertmanagerEvent
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.Alertmanager, error)
+	ConfigSecret(ctx context.Context, name string) (*coreV1.Secret, error)
+	Config(ctx context.Context, name string) ([]byte, error)
+	Silences(ctx context.Context, name string) (SilenceInterface, error)
+}
+
+type alertmanagers struct {
+	client     *versioned.Clientset
+	kubeClient *kubernetes.Clientset
+	restConfig *rest.Config
+	ns         string
+}
+
+func newAlertmanagers(c *versioned.Clientset, kubeClient *kubernetes.Clientset, restConfig *rest.Config, namespace string) *alertmanagers {
+	return &alertmanagers{
+		client:     c,
+		kubeClient: kubeClient,
+		restConfig: restConfig,
+		ns:         namespace,
+	}
+}
+
+func (a *alertmanagers) Create(ctx context.Context, alertmanager *v1.Alertmanager, opts metav1.CreateOptions) (*v1.Alertmanager, error) {
+	return a.client.MonitoringV1().
+		Alertmanagers(a.ns).
+		Create(ctx, alertmanager, opts)
+}
+
+func (a *alertmanagers) Update(ctx context.Context, alertmanager *v1.Alertmanager, opts metav1.UpdateOptions) (*v1.Alertmanager, error) {
+	return a.client.MonitoringV1().
+		Alertmanagers(a.ns).
+		Update(ctx, alertmanager, opts)
+}
+
+func (a *alertmanagers) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return a.client.MonitoringV1().
+		Alertmanagers(a.ns).
+		Delete(ctx, name, opts)
+}
+
+func (a *alertmanagers) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Alertmanager, error) {
+	return a.client.MonitoringV1().
+		Alertmanagers(a.ns).
+		Get(ctx, name, opts)
+}
+
+func (a *alertmanagers) List(ctx context.Context, opts metav1.ListOptions) (*v1.AlertmanagerList, error) {
+	return a.client.MonitoringV1().
+		Alertmanagers(a.ns).
+		List(ctx, opts)
+}
+
+func (a *alertmanagers) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return a.client.MonitoringV1().
+		Alertmanagers(a.ns).
+		Watch(ctx, opts)
+}
+
+func (a *alertmanagers) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.Alertmanager, error) {
+	return a.client.MonitoringV1().
+		Alertmanagers(a.ns).
+		Patch(ctx, name, pt, data, opts, subresources...)
+}
+
+// 返回Alertmanager使用的配置Secret,未指定spec.configSecret时operator使用alertmanager-<name>
+func (a *alertmanagers) ConfigSecret(ctx context.Context, name string) (*coreV1.Secret, error) {
+	am, err := a.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return a.kubeClient.CoreV1().
+		Secrets(a.ns).
+		Get(ctx, alertmanagerConfigSecretName(am), metav1.GetOptions{})
+}
+
+// 返回Alertmanager实际加载的alertmanager.yaml
+func (a *alertmanagers) Config(ctx context.Context, name string) ([]byte, error) {
+	secret, err := a.ConfigSecret(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	config, ok := secret.Data[alertmanagerConfigKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no %s", secret.Namespace, secret.Name, alertmanagerConfigKey)
+	}
+	return config, nil
+}
+
+func alertmanagerConfigSecretName(am *v1.Alertmanager) string {
+	if am.Spec.ConfigSecret != "" {
+		return am.Spec.ConfigSecret
+	}
+	return "alertmanager-" + am.Name
+}
@@ -0,0 +1,231 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/prometheus/client_golang/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// operator为每个namespace中的Alertmanager创建的headless Service
+	alertmanagerOperatedService = "alertmanager-operated"
+	alertmanagerDefaultPortName = "web"
+
+	silenceCreatedBy = "k8s-client"
+)
+
+// Matcher 静默匹配的告警label
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+}
+
+type SilenceStatus struct {
+	// active,pending或expired
+	State string `json:"state"`
+}
+
+// Silence Alertmanager v2 API中的静默
+type Silence struct {
+	ID        string         `json:"id,omitempty"`
+	Matchers  []Matcher      `json:"matchers"`
+	StartsAt  time.Time      `json:"startsAt"`
+	EndsAt    time.Time      `json:"endsAt"`
+	CreatedBy string         `json:"createdBy"`
+	Comment   string         `json:"comment"`
+	Status    *SilenceStatus `json:"status,omitempty"`
+}
+
+type SilenceInterface interface {
+	CreateSilence(ctx context.Context, silence Silence) (string, error)
+	ListSilences(ctx context.Context, matchers []Matcher) ([]Silence, error)
+	ExpireSilence(ctx context.Context, id string) error
+	WithSilence(ctx context.Context, matcherSets [][]Matcher, duration time.Duration, fn func(ctx context.Context) error) error
+}
+
+type silences struct {
+	client api.Client
+}
+
+// 返回通过API server的service proxy访问Alertmanager v2 API的静默client,
+// alertmanager-operated选择namespace中所有的Alertmanager,静默会在集群成员之间同步
+func (a *alertmanagers) Silences(ctx context.Context, name string) (SilenceInterface, error) {
+	am, err := a.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if am.Spec.ListenLocal {
+		return nil, fmt.Errorf("alertmanager %s/%s listens on localhost only", a.ns, name)
+	}
+
+	port := am.Spec.PortName
+	if port == "" {
+		port = alertmanagerDefaultPortName
+	}
+	client, err := serviceProxyClient(a.kubeClient, a.restConfig, a.ns, alertmanagerOperatedService, port, am.Spec.RoutePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &silences{client: client}, nil
+}
+
+// 创建静默,StartsAt为空时从现在开始,CreatedBy为空时使用k8s-client,返回静默的ID
+func (s *silences) CreateSilence(ctx context.Context, silence Silence) (string, error) {
+	if len(silence.Matchers) == 0 {
+		return "", fmt.Errorf("silence has no matchers")
+	}
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now()
+	}
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return "", fmt.Errorf("silence ends at %s before it starts", silence.EndsAt)
+	}
+	if silence.CreatedBy == "" {
+		silence.CreatedBy = silenceCreatedBy
+	}
+
+	data, err := json.Marshal(silence)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := s.do(ctx, http.MethodPost, s.client.URL("/api/v2/silences", nil).String(), data, &result); err != nil {
+		return "", err
+	}
+	return result.SilenceID, nil
+}
+
+// 列出匹配所有matchers的静默,包括已经过期的
+func (s *silences) ListSilences(ctx context.Context, matchers []Matcher) ([]Silence, error) {
+	u := s.client.URL("/api/v2/silences", nil)
+	q := u.Query()
+	for _, m := range matchers {
+		q.Add("filter", m.String())
+	}
+	u.RawQuery = q.Encode()
+
+	var result []Silence
+	if err := s.do(ctx, http.MethodGet, u.String(), nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *silences) ExpireSilence(ctx context.Context, id string) error {
+	return s.do(ctx, http.MethodDelete, s.client.URL("/api/v2/silence/:id", map[string]string{"id": id}).String(), nil, nil)
+}
+
+// 在fn执行期间静默匹配的告警,每组matchers创建一个静默,fn结束或panic后立即让静默过期.
+// duration为静默的最长时间,进程异常退出时静默也会自动结束
+func (s *silences) WithSilence(ctx context.Context, matcherSets [][]Matcher, duration time.Duration, fn func(ctx context.Context) error) (err error) {
+	var ids []string
+	defer func() {
+		if expireErr := s.expireSilences(ids); expireErr != nil {
+			if err != nil {
+				err = fmt.Errorf("%v, %v", err, expireErr)
+			} else {
+				err = expireErr
+			}
+		}
+	}()
+
+	now := time.Now()
+	for _, matchers := range matcherSets {
+		id, err := s.CreateSilence(ctx, Silence{
+			Matchers: matchers,
+			StartsAt: now,
+			EndsAt:   now.Add(duration),
+			Comment:  "silenced by k8s-client while the operation runs",
+		})
+		if err != nil {
+			return fmt.Errorf("create silence err: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return fn(ctx)
+}
+
+// ctx可能已经结束,使用新的ctx让静默过期
+func (s *silences) expireSilences(ids []string) error {
+	expireCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var errs []string
+	for _, id := range ids {
+		if err := s.ExpireSilence(expireCtx, id); err != nil {
+			errs = append(errs, fmt.Sprintf("expire silence %s err: %v", id, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (s *silences) do(ctx context.Context, method, url string, body []byte, result interface{}) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, data, err := s.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s: %s", method, req.URL.Path, resp.Status, bytes.TrimSpace(data))
+	}
+
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// Alertmanager API中filter参数的格式
+func (m Matcher) String() string {
+	op := "="
+	if m.IsRegex {
+		op = "=~"
+	}
+	return fmt.Sprintf("%s%s%q", m.Name, op, m.Value)
+}
+
+// 匹配deployment所有pod的告警,pod名称为<deployment>-<pod-template-hash>-<随机后缀>
+func DeploymentMatchers(namespace, deployment string) []Matcher {
+	return []Matcher{
+		{Name: "namespace", Value: namespace},
+		{Name: "pod", Value: regexp.QuoteMeta(deployment) + "-[a-z0-9]+-[a-z0-9]+", IsRegex: true},
+	}
+}
+
+// 匹配deployment本身的告警,例如kube-state-metrics的副本数告警只有deployment label
+func DeploymentAlertMatchers(namespace, deployment string) []Matcher {
+	return []Matcher{
+		{Name: "namespace", Value: namespace},
+		{Name: "deployment", Value: deployment},
+	}
+}
+
+// WithSilence使用的deployment静默,同时覆盖pod和deployment的告警
+func DeploymentSilenceMatchers(namespace, deployment string) [][]Matcher {
+	return [][]Matcher{
+		DeploymentMatchers(namespace, deployment),
+		DeploymentAlertMatchers(namespace, deployment),
+	}
+}
@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/prometheus/client_golang/api"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSilences_WithSilence(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		created []Silence
+		expired []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/silences":
+			var silence Silence
+			if err := json.NewDecoder(r.Body).Decode(&silence); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			created = append(created, silence)
+			fmt.Fprintf(w, `{"silenceID":"id-%d"}`, len(created))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+			expired = append(expired, strings.TrimPrefix(r.URL.Path, "/api/v2/silence/"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := api.NewClient(api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &silences{client: client}
+
+	ran := false
+	err = s.WithSilence(context.Background(), DeploymentSilenceMatchers("dev1-xiaomai-server", "app-forum"), time.Hour, func(ctx context.Context) error {
+		ran = true
+		mu.Lock()
+		defer mu.Unlock()
+		if len(expired) != 0 {
+			t.Fatal("silence expired before fn finished")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ran || fmt.Sprint(expired) != "[id-1 id-2]" {
+		t.Fatalf("ran = %v, expired = %q", ran, expired)
+	}
+	if len(created) != 2 || created[0].CreatedBy != silenceCreatedBy || !created[0].EndsAt.After(created[0].StartsAt) {
+		t.Fatalf("created = %+v", created)
+	}
+	if created[1].Matchers[1].Name != "deployment" || created[1].Matchers[1].Value != "app-forum" {
+		t.Fatalf("deployment silence = %+v", created[1])
+	}
+
+	// fn panic时静默也要过期
+	created, expired = nil, nil
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic")
+			}
+		}()
+		s.WithSilence(context.Background(), DeploymentSilenceMatchers("dev1-xiaomai-server", "app-forum"), time.Hour, func(ctx context.Context) error {
+			panic("boom")
+		})
+	}()
+	if fmt.Sprint(expired) != "[id-1 id-2]" {
+		t.Fatalf("expired after panic = %q", expired)
+	}
+
+	if _, err := s.ListSilences(context.Background(), nil); err == nil {
+		t.Fatal("expected error for 404")
+	}
+}
+
+func TestDeploymentMatchers(t *testing.T) {
+	matchers := DeploymentMatchers("dev1-xiaomai-server", "app-forum")
+	re := regexp.MustCompile("^(?:" + matchers[1].Value + ")$")
+	if !re.MatchString("app-forum-5d9b6f84fb-g2pl6") {
+		t.Fatal("pod of deployment should match")
+	}
+	if re.MatchString("app-forum-admin-5d9b6f84fb-g2pl6") {
+		t.Fatal("pod of another deployment should not match")
+	}
+	if got := matchers[1].String(); !strings.HasPrefix(got, `pod=~"app-forum-`) {
+		t.Fatalf("filter = %s", got)
+	}
+}
@@ -0,0 +1,19 @@
+package v1
+
+import (
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestAlertmanagerConfigSecretName(t *testing.T) {
+	am := &v1.Alertmanager{ObjectMeta: metav1.ObjectMeta{Name: "main"}}
+	if name := alertmanagerConfigSecretName(am); name != "alertmanager-main" {
+		t.Fatalf("default secret name = %s", name)
+	}
+
+	am.Spec.ConfigSecret = "alertmanager-custom"
+	if name := alertmanagerConfigSecretName(am); name != "alertmanager-custom" {
+		t.Fatalf("secret name = %s", name)
+	}
+}
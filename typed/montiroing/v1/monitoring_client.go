@@ -2,17 +2,24 @@ package v1
 
 import (
 	"github.com/coreos/prometheus-operator/pkg/client/versioned"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 type PrometheusMonitoringInterface interface {
 	PrometheusGetter
 	PrometheusRulesGetter
-	//ServiceMonitorsGetter
+	ServiceMonitorsGetter
+	PodMonitorsGetter
+	AlertmanagersGetter
+	ThanosRulersGetter
+	ProbesGetter
 }
 
 type PrometheusMonitoring struct {
-	client *versioned.Clientset
+	client     *versioned.Clientset
+	kubeClient *kubernetes.Clientset
+	restConfig *rest.Config
 }
 
 func NewForConfig(c *rest.Config) (*PrometheusMonitoring, error) {
@@ -22,16 +29,43 @@ func NewForConfig(c *rest.Config) (*PrometheusMonitoring, error) {
 		return nil, err
 	}
 
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &PrometheusMonitoring{
-		client: client,
+		client:     client,
+		kubeClient: kubeClient,
+		restConfig: c,
 	}, nil
 
 }
 
 func (c *PrometheusMonitoring) Prometheuses(namespace string) PrometheusInterface {
-	return newPrometheuses(c.client, namespace)
+	return newPrometheuses(c.client, c.kubeClient, c.restConfig, namespace)
 }
 
 func (c *PrometheusMonitoring) PrometheusRules(namespace string) PrometheusRuleInterface {
 	return newPrometheusRules(c.client, namespace)
 }
+
+func (c *PrometheusMonitoring) ServiceMonitors(namespace string) ServiceMonitorInterface {
+	return newServiceMonitors(c.client, namespace)
+}
+
+func (c *PrometheusMonitoring) PodMonitors(namespace string) PodMonitorInterface {
+	return newPodMonitors(c.client, namespace)
+}
+
+func (c *PrometheusMonitoring) Alertmanagers(namespace string) AlertmanagerInterface {
+	return newAlertmanagers(c.client, c.kubeClient, c.restConfig, namespace)
+}
+
+func (c *PrometheusMonitoring) ThanosRulers(namespace string) ThanosRulerInterface {
+	return newThanosRulers(c.client, namespace)
+}
+
+func (c *PrometheusMonitoring) Probes(namespace string) ProbeInterface {
+	return newProbes(c.client, namespace)
+}
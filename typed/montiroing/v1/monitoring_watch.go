@@ -0,0 +1,382 @@
+package v1
+
+import (
+	"context"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog"
+	"net/http"
+	"time"
+)
+
+const (
+	watchRetryMin = time.Second
+	watchRetryMax = 30 * time.Second
+)
+
+type PrometheusEvent struct {
+	Type   watch.EventType
+	Object *v1.Prometheus
+}
+
+type PrometheusRuleEvent struct {
+	Type   watch.EventType
+	Object *v1.PrometheusRule
+}
+
+type ServiceMonitorEvent struct {
+	Type   watch.EventType
+	Object *v1.ServiceMonitor
+}
+
+type PodMonitorEvent struct {
+	Type   watch.EventType
+	Object *v1.PodMonitor
+}
+
+type AlertmanagerEvent struct {
+	Type   watch.EventType
+	Object *v1.Alertmanager
+}
+
+type ThanosRulerEvent struct {
+	Type   watch.EventType
+	Object *v1.ThanosRuler
+}
+
+type ProbeEvent struct {
+	Type   watch.EventType
+	Object *v1.Probe
+}
+
+// 持续watch Prometheus,opts.ResourceVersion为空时先list并把已有对象作为Added返回.
+// resourceVersion过期时重新list,和之前的结果比较后补发Added,Modified和Deleted事件.
+// ctx结束时关闭channel
+func (p *prometheuses) WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan PrometheusEvent {
+	ch := make(chan PrometheusEvent)
+	go func() {
+		defer close(ch)
+		watchObjects(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return p.List(ctx, opts)
+		}, p.Watch, func(event watch.Event) bool {
+			obj, ok := event.Object.(*v1.Prometheus)
+			if !ok {
+				return true
+			}
+			select {
+			case ch <- PrometheusEvent{Type: event.Type, Object: obj}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+func (p *prometheusRules) WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan PrometheusRuleEvent {
+	ch := make(chan PrometheusRuleEvent)
+	go func() {
+		defer close(ch)
+		watchObjects(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return p.List(ctx, opts)
+		}, p.Watch, func(event watch.Event) bool {
+			obj, ok := event.Object.(*v1.PrometheusRule)
+			if !ok {
+				return true
+			}
+			select {
+			case ch <- PrometheusRuleEvent{Type: event.Type, Object: obj}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+func (s *serviceMonitors) WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan ServiceMonitorEvent {
+	ch := make(chan ServiceMonitorEvent)
+	go func() {
+		defer close(ch)
+		watchObjects(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return s.List(ctx, opts)
+		}, s.Watch, func(event watch.Event) bool {
+			obj, ok := event.Object.(*v1.ServiceMonitor)
+			if !ok {
+				return true
+			}
+			select {
+			case ch <- ServiceMonitorEvent{Type: event.Type, Object: obj}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+func (p *podMonitors) WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan PodMonitorEvent {
+	ch := make(chan PodMonitorEvent)
+	go func() {
+		defer close(ch)
+		watchObjects(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return p.List(ctx, opts)
+		}, p.Watch, func(event watch.Event) bool {
+			obj, ok := event.Object.(*v1.PodMonitor)
+			if !ok {
+				return true
+			}
+			select {
+			case ch <- PodMonitorEvent{Type: event.Type, Object: obj}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+func (a *alertmanagers) WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan AlertmanagerEvent {
+	ch := make(chan AlertmanagerEvent)
+	go func() {
+		defer close(ch)
+		watchObjects(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return a.List(ctx, opts)
+		}, a.Watch, func(event watch.Event) bool {
+			obj, ok := event.Object.(*v1.Alertmanager)
+			if !ok {
+				return true
+			}
+			select {
+			case ch <- AlertmanagerEvent{Type: event.Type, Object: obj}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+func (t *thanosRulers) WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan ThanosRulerEvent {
+	ch := make(chan ThanosRulerEvent)
+	go func() {
+		defer close(ch)
+		watchObjects(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return t.List(ctx, opts)
+		}, t.Watch, func(event watch.Event) bool {
+			obj, ok := event.Object.(*v1.ThanosRuler)
+			if !ok {
+				return true
+			}
+			select {
+			case ch <- ThanosRulerEvent{Type: event.Type, Object: obj}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+func (p *probes) WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan ProbeEvent {
+	ch := make(chan ProbeEvent)
+	go func() {
+		defer close(ch)
+		watchObjects(ctx, opts, func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return p.List(ctx, opts)
+		}, p.Watch, func(event watch.Event) bool {
+			obj, ok := event.Object.(*v1.Probe)
+			if !ok {
+				return true
+			}
+			select {
+			case ch <- ProbeEvent{Type: event.Type, Object: obj}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+type listFunc func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+
+type watchFunc func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+
+// watchObjects 和pod的watchUntil一样先list再从list的resourceVersion开始watch,
+// watch断开后从最后的resourceVersion继续,过期时重新list.
+// 其他错误按指数退避重试,直到ctx结束或emit返回false
+func watchObjects(ctx context.Context, opts metav1.ListOptions, list listFunc, watchFn watchFunc, emit func(event watch.Event) bool) {
+	known := make(map[string]runtime.Object)
+	resourceVersion := opts.ResourceVersion
+	retry := watchRetryMin
+
+	backoff := func(action string, err error) bool {
+		klog.Warningf("%s monitoring objects err: %v, retrying in %s", action, err, retry)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(retry):
+		}
+		if retry *= 2; retry > watchRetryMax {
+			retry = watchRetryMax
+		}
+		return true
+	}
+
+	for ctx.Err() == nil {
+		if resourceVersion == "" {
+			listOpts := opts
+			listOpts.ResourceVersion = ""
+			obj, err := list(ctx, listOpts)
+			if err == nil {
+				resourceVersion, err = relist(obj, known, emit)
+			}
+			if err != nil {
+				if ctx.Err() != nil || !backoff("list", err) {
+					return
+				}
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		watchOpts := opts
+		watchOpts.ResourceVersion = resourceVersion
+		w, err := watchFn(ctx, watchOpts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+				resourceVersion = ""
+				continue
+			}
+			if !backoff("watch", err) {
+				return
+			}
+			continue
+		}
+		retry = watchRetryMin
+
+		var stop bool
+		resourceVersion, stop, err = consumeWatch(ctx, w, resourceVersion, known, emit)
+		if stop {
+			return
+		}
+		if err != nil && !backoff("watch", err) {
+			return
+		}
+	}
+}
+
+// relist 用list的结果替换known,对新对象发送Added,resourceVersion变化的发送Modified,
+// 不再存在的发送Deleted.返回list的resourceVersion
+func relist(obj runtime.Object, known map[string]runtime.Object, emit func(event watch.Event) bool) (string, error) {
+	listMeta, err := meta.ListAccessor(obj)
+	if err != nil {
+		return "", err
+	}
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		return "", err
+	}
+
+	current := make(map[string]runtime.Object, len(items))
+	for _, item := range items {
+		key, rv := objectKey(item)
+		current[key] = item
+
+		eventType := watch.Added
+		if previous, ok := known[key]; ok {
+			if _, previousRV := objectKey(previous); previousRV == rv {
+				continue
+			}
+			eventType = watch.Modified
+		}
+		if !emit(watch.Event{Type: eventType, Object: item}) {
+			return listMeta.GetResourceVersion(), nil
+		}
+	}
+
+	for key, item := range known {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		delete(known, key)
+		if !emit(watch.Event{Type: watch.Deleted, Object: item}) {
+			return listMeta.GetResourceVersion(), nil
+		}
+	}
+
+	for key, item := range current {
+		known[key] = item
+	}
+	return listMeta.GetResourceVersion(), nil
+}
+
+// consumeWatch 处理watch事件直到watch断开,返回最后的resourceVersion,
+// resourceVersion过期时返回空字符串.ctx结束或emit返回false时返回stop
+func consumeWatch(ctx context.Context, w watch.Interface, resourceVersion string, known map[string]runtime.Object, emit func(event watch.Event) bool) (string, bool, error) {
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, true, nil
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion, false, nil
+			}
+
+			switch event.Type {
+			case watch.Added, watch.Modified, watch.Deleted:
+				key, rv := objectKey(event.Object)
+				if key == "" {
+					continue
+				}
+				resourceVersion = rv
+				if event.Type == watch.Deleted {
+					delete(known, key)
+				} else {
+					known[key] = event.Object
+				}
+				if !emit(event) {
+					return resourceVersion, true, nil
+				}
+			case watch.Bookmark:
+				if _, rv := objectKey(event.Object); rv != "" {
+					resourceVersion = rv
+				}
+			case watch.Error:
+				status, ok := event.Object.(*metav1.Status)
+				if ok && (status.Code == http.StatusGone || status.Reason == metav1.StatusReasonExpired) {
+					return "", false, nil
+				}
+				return resourceVersion, false, apierrors.FromObject(event.Object)
+			}
+		}
+	}
+}
+
+// objectKey 返回namespace/name和resourceVersion
+func objectKey(obj runtime.Object) (string, string) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", ""
+	}
+	return accessor.GetNamespace() + "/" + accessor.GetName(), accessor.GetResourceVersion()
+}
@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func testServiceMonitor(name, resourceVersion string) *v1.ServiceMonitor {
+	return &v1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: name, ResourceVersion: resourceVersion}}
+}
+
+func TestWatchObjectsRelistOnExpired(t *testing.T) {
+	lists := []*v1.ServiceMonitorList{
+		{
+			ListMeta: metav1.ListMeta{ResourceVersion: "10"},
+			Items:    []*v1.ServiceMonitor{testServiceMonitor("a", "1"), testServiceMonitor("b", "2")},
+		},
+		{
+			ListMeta: metav1.ListMeta{ResourceVersion: "20"},
+			Items:    []*v1.ServiceMonitor{testServiceMonitor("a", "11"), testServiceMonitor("c", "12")},
+		},
+	}
+	watchers := []*watch.FakeWatcher{watch.NewFake(), watch.NewFake()}
+	var watchedFrom []string
+
+	list := func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		if len(lists) == 0 {
+			return nil, fmt.Errorf("unexpected list")
+		}
+		l := lists[0]
+		lists = lists[1:]
+		return l, nil
+	}
+	watchFn := func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+		if len(watchers) == 0 {
+			return nil, fmt.Errorf("unexpected watch")
+		}
+		watchedFrom = append(watchedFrom, opts.ResourceVersion)
+		w := watchers[0]
+		watchers = watchers[1:]
+		return w, nil
+	}
+
+	first, second := watchers[0], watchers[1]
+	go func() {
+		first.Modify(testServiceMonitor("a", "11"))
+		first.Error(&metav1.Status{Code: http.StatusGone, Reason: metav1.StatusReasonExpired})
+		second.Delete(testServiceMonitor("c", "13"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got []string
+	watchObjects(ctx, metav1.ListOptions{}, list, watchFn, func(event watch.Event) bool {
+		sm := event.Object.(*v1.ServiceMonitor)
+		got = append(got, fmt.Sprintf("%s %s/%s", event.Type, sm.Name, sm.ResourceVersion))
+		if event.Type == watch.Deleted && sm.Name == "c" {
+			cancel()
+		}
+		return true
+	})
+
+	want := []string{
+		"ADDED a/1", "ADDED b/2",
+		"MODIFIED a/11",
+		// 重新list时a没有变化,b已经被删除
+		"ADDED c/12", "DELETED b/2",
+		"DELETED c/13",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if fmt.Sprint(watchedFrom) != fmt.Sprint([]string{"10", "20"}) {
+		t.Fatalf("watched from %v", watchedFrom)
+	}
+}
@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"context"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/coreos/prometheus-operator/pkg/client/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type PodMonitorsGetter interface {
+	PodMonitors(namespace string) PodMonitorInterface
+}
+
+type PodMonitorInterface interface {
+	Create(ctx context.Context, podMonitor *v1.PodMonitor, opts metav1.CreateOptions) (*v1.PodMonitor, error)
+	Update(ctx context.Context, podMonitor *v1.PodMonitor, opts metav1.UpdateOptions) (*v1.PodMonitor, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	//DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.PodMonitor, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.PodMonitorList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan PodMonitorEvent
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.PodMonitor, error)
+}
+
+type podMonitors struct {
+	client *versioned.Clientset
+	ns     string
+}
+
+func newPodMonitors(c *versioned.Clientset, namespace string) *podMonitors {
+	return &podMonitors{
+		client: c,
+		ns:     namespace,
+	}
+}
+
+func (p *podMonitors) Create(ctx context.Context, podMonitor *v1.PodMonitor, opts metav1.CreateOptions) (*v1.PodMonitor, error) {
+	return p.client.MonitoringV1().
+		PodMonitors(p.ns).
+		Create(ctx, podMonitor, opts)
+}
+
+func (p *podMonitors) Update(ctx context.Context, podMonitor *v1.PodMonitor, opts metav1.UpdateOptions) (*v1.PodMonitor, error) {
+	return p.client.MonitoringV1().
+		PodMonitors(p.ns).
+		Update(ctx, podMonitor, opts)
+}
+
+func (p *podMonitors) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return p.client.MonitoringV1().
+		PodMonitors(p.ns).
+		Delete(ctx, name, opts)
+}
+
+func (p *podMonitors) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.PodMonitor, error) {
+	return p.client.MonitoringV1().
+		PodMonitors(p.ns).
+		Get(ctx, name, opts)
+}
+
+func (p *podMonitors) List(ctx context.Context, opts metav1.ListOptions) (*v1.PodMonitorList, error) {
+	return p.client.MonitoringV1().
+		PodMonitors(p.ns).
+		List(ctx, opts)
+}
+
+func (p *podMonitors) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return p.client.MonitoringV1().
+		PodMonitors(p.ns).
+		Watch(ctx, opts)
+}
+
+func (p *podMonitors) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.PodMonitor, error) {
+	return p.client.MonitoringV1().
+		PodMonitors(p.ns).
+		Patch(ctx, name, pt, data, opts, subresources...)
+}
@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"context"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/coreos/prometheus-operator/pkg/client/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type ProbesGetter interface {
+	Probes(namespace string) ProbeInterface
+}
+
+type ProbeInterface interface {
+	Create(ctx context.Context, probe *v1.Probe, opts metav1.CreateOptions) (*v1.Probe, error)
+	Update(ctx context.Context, probe *v1.Probe, opts metav1.UpdateOptions) (*v1.Probe, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	//DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Probe, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.ProbeList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan ProbeEvent
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.Probe, error)
+}
+
+type probes struct {
+	client *versioned.Clientset
+	ns     string
+}
+
+func newProbes(c *versioned.Clientset, namespace string) *probes {
+	return &probes{
+		client: c,
+		ns:     namespace,
+	}
+}
+
+func (p *probes) Create(ctx context.Context, probe *v1.Probe, opts metav1.CreateOptions) (*v1.Probe, error) {
+	return p.client.MonitoringV1().
+		Probes(p.ns).
+		Create(ctx, probe, opts)
+}
+
+func (p *probes) Update(ctx context.Context, probe *v1.Probe, opts metav1.UpdateOptions) (*v1.Probe, error) {
+	return p.client.MonitoringV1().
+		Probes(p.ns).
+		Update(ctx, probe, opts)
+}
+
+func (p *probes) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return p.client.MonitoringV1().
+		Probes(p.ns).
+		Delete(ctx, name, opts)
+}
+
+func (p *probes) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Probe, error) {
+	return p.client.MonitoringV1().
+		Probes(p.ns).
+		Get(ctx, name, opts)
+}
+
+func (p *probes) List(ctx context.Context, opts metav1.ListOptions) (*v1.ProbeList, error) {
+	return p.client.MonitoringV1().
+		Probes(p.ns).
+		List(ctx, opts)
+}
+
+func (p *probes) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return p.client.MonitoringV1().
+		Probes(p.ns).
+		Watch(ctx, opts)
+}
+
+func (p *probes) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.Probe, error) {
+	return p.client.MonitoringV1().
+		Probes(p.ns).
+		Patch(ctx, name, pt, data, opts, subresources...)
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/coreos/prometheus-operator/pkg/client/versioned"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 type PrometheusGetter interface {
@@ -19,18 +22,25 @@ type PrometheusInterface interface {
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Prometheus, error)
 	List(ctx context.Context, opts metav1.ListOptions) (*v1.PrometheusList, error)
-	Watch(ctx context.Context) (watch.Interface, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan PrometheusEvent
+	QueryClient(ctx context.Context, name string) (promv1.API, error)
+	Status(ctx context.Context, name string) (*PrometheusStatus, error)
 }
 
 type prometheuses struct {
-	client *versioned.Clientset
-	ns     string
+	client     *versioned.Clientset
+	kubeClient *kubernetes.Clientset
+	restConfig *rest.Config
+	ns         string
 }
 
-func newPrometheuses(c *versioned.Clientset, namespace string) *prometheuses {
+func newPrometheuses(c *versioned.Clientset, kubeClient *kubernetes.Clientset, restConfig *rest.Config, namespace string) *prometheuses {
 	return &prometheuses{
-		client: c,
-		ns:     namespace,
+		client:     c,
+		kubeClient: kubeClient,
+		restConfig: restConfig,
+		ns:         namespace,
 	}
 }
 
@@ -64,8 +74,7 @@ func (p *prometheuses) List(ctx context.Context, opts metav1.ListOptions) (*v1.P
 		List(ctx, opts)
 }
 
-func (p *prometheuses) Watch(ctx context.Context) (watch.Interface, error) {
-	var opts metav1.ListOptions
+func (p *prometheuses) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
 	return p.client.MonitoringV1().
 		Prometheuses(p.ns).
 		Watch(ctx, opts)
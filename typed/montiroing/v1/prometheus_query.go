@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"net/url"
+	"path"
+)
+
+// operator生成的Prometheus容器web端口,pod proxy只支持端口号
+const prometheusWebPortNumber = "9090"
+
+// 返回通过API server的pod proxy访问Prometheus HTTP API的client,
+// 支持即时查询,范围查询,series,labels,targets和alerts.
+// 请求发送到operator为这个Prometheus创建的StatefulSet中的一个ready pod
+func (p *prometheuses) QueryClient(ctx context.Context, name string) (promv1.API, error) {
+	prom, err := p.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	sts, err := p.kubeClient.AppsV1().StatefulSets(p.ns).Get(ctx, prometheusStatefulSetName(name), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := p.podStatuses(ctx, sts)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := p.instanceClient(prom, pods)
+	if err != nil {
+		return nil, err
+	}
+	return promv1.NewAPI(client), nil
+}
+
+// serviceProxyClient 返回通过API server的service proxy访问Service的HTTP client
+func serviceProxyClient(kubeClient *kubernetes.Clientset, restConfig *rest.Config, namespace, service, port, routePrefix string) (api.Client, error) {
+	return proxyClient(restConfig, serviceProxyURL(kubeClient, namespace, service, port, routePrefix))
+}
+
+// podProxyClient 返回通过API server的pod proxy访问单个pod的HTTP client,pod proxy只支持端口号
+func podProxyClient(kubeClient *kubernetes.Clientset, restConfig *rest.Config, namespace, pod, port, routePrefix string) (api.Client, error) {
+	return proxyClient(restConfig, proxyURL(kubeClient, namespace, "pods", pod, port, routePrefix))
+}
+
+func proxyClient(restConfig *rest.Config, address *url.URL) (api.Client, error) {
+	transport, err := rest.TransportFor(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.NewClient(api.Config{
+		Address:      address.String(),
+		RoundTripper: transport,
+	})
+}
+
+func serviceProxyURL(kubeClient *kubernetes.Clientset, namespace, service, port, routePrefix string) *url.URL {
+	return proxyURL(kubeClient, namespace, "services", service, port, routePrefix)
+}
+
+func proxyURL(kubeClient *kubernetes.Clientset, namespace, resource, name, port, routePrefix string) *url.URL {
+	address := kubeClient.CoreV1().RESTClient().Get().
+		Namespace(namespace).
+		Resource(resource).
+		Name("http:" + name + ":" + port).
+		SubResource("proxy").
+		URL()
+	if routePrefix != "" {
+		address.Path = path.Join(address.Path, routePrefix)
+	}
+	return address
+}
@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"testing"
+)
+
+func TestServiceProxyURL(t *testing.T) {
+	kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: "https://10.0.0.1:6443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		prefix string
+		want   string
+	}{
+		{"", "https://10.0.0.1:6443/api/v1/namespaces/monitoring/services/http:alertmanager-operated:web/proxy"},
+		{"/alertmanager", "https://10.0.0.1:6443/api/v1/namespaces/monitoring/services/http:alertmanager-operated:web/proxy/alertmanager"},
+	}
+	for _, c := range cases {
+		got := serviceProxyURL(kubeClient, "monitoring", alertmanagerOperatedService, alertmanagerDefaultPortName, c.prefix).String()
+		if got != c.want {
+			t.Fatalf("serviceProxyURL(%q) = %s, want %s", c.prefix, got, c.want)
+		}
+	}
+}
+
+func TestPodProxyURL(t *testing.T) {
+	kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: "https://10.0.0.1:6443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := proxyURL(kubeClient, "monitoring", "pods", "prometheus-k8s-0", prometheusWebPortNumber, "/prometheus").String()
+	want := "https://10.0.0.1:6443/api/v1/namespaces/monitoring/pods/http:prometheus-k8s-0:9090/proxy/prometheus"
+	if got != want {
+		t.Fatalf("proxyURL = %s, want %s", got, want)
+	}
+}
@@ -20,6 +20,7 @@ type PrometheusRuleInterface interface {
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.PrometheusRule, error)
 	List(ctx context.Context, opts metav1.ListOptions) (*v1.PrometheusRuleList, error)
 	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan PrometheusRuleEvent
 }
 
 // prometheusRules implements PrometheusRuleInterface
@@ -36,13 +37,23 @@ func newPrometheusRules(c *versioned.Clientset, namespace string) *prometheusRul
 	}
 }
 
+// 创建之前使用ValidatePrometheusRule校验
 func (p *prometheusRules) Create(ctx context.Context, prometheusRule *v1.PrometheusRule, opts metav1.CreateOptions) (*v1.PrometheusRule, error) {
+	if err := ValidatePrometheusRule(prometheusRule); err != nil {
+		return nil, err
+	}
+
 	return p.client.MonitoringV1().
 		PrometheusRules(p.ns).
 		Create(ctx, prometheusRule, opts)
 }
 
+// 更新之前使用ValidatePrometheusRule校验
 func (p *prometheusRules) Update(ctx context.Context, prometheusRule *v1.PrometheusRule, opts metav1.UpdateOptions) (*v1.PrometheusRule, error) {
+	if err := ValidatePrometheusRule(prometheusRule); err != nil {
+		return nil, err
+	}
+
 	return p.client.MonitoringV1().
 		PrometheusRules(p.ns).
 		Update(ctx, prometheusRule, opts)
@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"fmt"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// PrometheusRuleBuilder 链式构建PrometheusRule,
+// Interval作用于最后一个Group,For,Label和Annotation作用于最后一条规则
+//
+//	rule, err := NewPrometheusRule("monitoring", "app-forum").
+//		Labels(map[string]string{"prometheus": "k8s"}).
+//		Group("app-forum.rules").
+//		Record("job:http_requests:rate5m", `sum by (job) (rate(http_requests_total[5m]))`).
+//		Alert("HighErrorRate", `job:http_errors:rate5m > 0.05`).For("10m").
+//		Label("severity", "critical").
+//		Annotation("summary", "{{ $labels.job }} error rate is {{ $value }}").
+//		Build()
+type PrometheusRuleBuilder struct {
+	rule *v1.PrometheusRule
+	errs []error
+}
+
+func NewPrometheusRule(namespace, name string) *PrometheusRuleBuilder {
+	return &PrometheusRuleBuilder{
+		rule: &v1.PrometheusRule{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1.SchemeGroupVersion.String(),
+				Kind:       v1.PrometheusRuleKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+		},
+	}
+}
+
+// PrometheusRule的label,需要匹配Prometheus的ruleSelector
+func (b *PrometheusRuleBuilder) Labels(labels map[string]string) *PrometheusRuleBuilder {
+	if b.rule.Labels == nil {
+		b.rule.Labels = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		b.rule.Labels[k] = v
+	}
+	return b
+}
+
+func (b *PrometheusRuleBuilder) Group(name string) *PrometheusRuleBuilder {
+	b.rule.Spec.Groups = append(b.rule.Spec.Groups, v1.RuleGroup{Name: name})
+	return b
+}
+
+func (b *PrometheusRuleBuilder) Interval(interval string) *PrometheusRuleBuilder {
+	if group := b.lastGroup("Interval"); group != nil {
+		group.Interval = interval
+	}
+	return b
+}
+
+func (b *PrometheusRuleBuilder) Alert(name, expr string) *PrometheusRuleBuilder {
+	if group := b.lastGroup("Alert"); group != nil {
+		group.Rules = append(group.Rules, v1.Rule{Alert: name, Expr: intstr.FromString(expr)})
+	}
+	return b
+}
+
+func (b *PrometheusRuleBuilder) Record(name, expr string) *PrometheusRuleBuilder {
+	if group := b.lastGroup("Record"); group != nil {
+		group.Rules = append(group.Rules, v1.Rule{Record: name, Expr: intstr.FromString(expr)})
+	}
+	return b
+}
+
+func (b *PrometheusRuleBuilder) For(duration string) *PrometheusRuleBuilder {
+	if rule := b.lastRule("For"); rule != nil {
+		rule.For = duration
+	}
+	return b
+}
+
+func (b *PrometheusRuleBuilder) Label(name, value string) *PrometheusRuleBuilder {
+	if rule := b.lastRule("Label"); rule != nil {
+		if rule.Labels == nil {
+			rule.Labels = make(map[string]string)
+		}
+		rule.Labels[name] = value
+	}
+	return b
+}
+
+func (b *PrometheusRuleBuilder) Annotation(name, value string) *PrometheusRuleBuilder {
+	if rule := b.lastRule("Annotation"); rule != nil {
+		if rule.Annotations == nil {
+			rule.Annotations = make(map[string]string)
+		}
+		rule.Annotations[name] = value
+	}
+	return b
+}
+
+// 返回构建的PrometheusRule,链式调用的错误和ValidatePrometheusRule的错误一起返回
+func (b *PrometheusRuleBuilder) Build() (*v1.PrometheusRule, error) {
+	errs := append([]error{}, b.errs...)
+	if err := ValidatePrometheusRule(b.rule); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return b.rule.DeepCopy(), nil
+}
+
+func (b *PrometheusRuleBuilder) lastGroup(method string) *v1.RuleGroup {
+	groups := b.rule.Spec.Groups
+	if len(groups) == 0 {
+		b.errs = append(b.errs, fmt.Errorf("%s called before Group", method))
+		return nil
+	}
+	return &groups[len(groups)-1]
+}
+
+func (b *PrometheusRuleBuilder) lastRule(method string) *v1.Rule {
+	group := b.lastGroup(method)
+	if group == nil {
+		return nil
+	}
+	if len(group.Rules) == 0 {
+		b.errs = append(b.errs, fmt.Errorf("group %s: %s called before Alert or Record", group.Name, method))
+		return nil
+	}
+	return &group.Rules[len(group.Rules)-1]
+}
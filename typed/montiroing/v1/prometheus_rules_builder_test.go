@@ -0,0 +1,85 @@
+package v1
+
+import (
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusRuleBuilder(t *testing.T) {
+	rule, err := NewPrometheusRule("monitoring", "app-forum").
+		Labels(map[string]string{"prometheus": "k8s"}).
+		Group("app-forum.rules").Interval("30s").
+		Record("job:http_requests:rate5m", `sum by (job) (rate(http_requests_total[5m]))`).
+		Alert("HighErrorRate", `job:http_errors:rate5m > 0.05`).For("10m").
+		Label("severity", "critical").
+		Annotation("summary", "{{ $labels.job }} error rate is {{ $value | humanizePercentage }}").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rule.Namespace != "monitoring" || rule.Labels["prometheus"] != "k8s" {
+		t.Fatalf("metadata = %+v", rule.ObjectMeta)
+	}
+	group := rule.Spec.Groups[0]
+	if group.Interval != "30s" || len(group.Rules) != 2 {
+		t.Fatalf("group = %+v", group)
+	}
+	alert := group.Rules[1]
+	if alert.For != "10m" || alert.Labels["severity"] != "critical" || alert.Annotations["summary"] == "" {
+		t.Fatalf("alert = %+v", alert)
+	}
+}
+
+func TestPrometheusRuleBuilder_Errors(t *testing.T) {
+	_, err := NewPrometheusRule("monitoring", "app-forum").
+		Alert("NoGroup", `up == 0`).
+		Group("app-forum.rules").
+		For("5m").
+		Build()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"Alert called before Group", "For called before Alert or Record"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestValidatePrometheusRule(t *testing.T) {
+	cases := []struct {
+		name  string
+		rules []v1.Rule
+		want  string
+	}{
+		{"expr", []v1.Rule{{Alert: "Down", Expr: intstr.FromString("up ==")}}, "invalid expr"},
+		{"empty expr", []v1.Rule{{Alert: "Down"}}, "expr is empty"},
+		{"for", []v1.Rule{{Alert: "Down", Expr: intstr.FromString("up == 0"), For: "5 minutes"}}, "invalid for"},
+		{"template", []v1.Rule{{Alert: "Down", Expr: intstr.FromString("up == 0"), Annotations: map[string]string{"summary": "{{ $labels.job "}}}, `annotation "summary"`},
+		{"record name", []v1.Rule{{Record: "job-up", Expr: intstr.FromString("up")}}, "invalid recording rule name"},
+		{"record and alert", []v1.Rule{{Record: "job:up", Alert: "Down", Expr: intstr.FromString("up")}}, "exactly one of record and alert"},
+		{"duplicate", []v1.Rule{
+			{Alert: "Down", Expr: intstr.FromString("up == 0"), Labels: map[string]string{"severity": "critical"}},
+			{Alert: "Down", Expr: intstr.FromString("up == 0"), Labels: map[string]string{"severity": "critical"}},
+		}, "duplicate of alert"},
+	}
+	for _, c := range cases {
+		rule := &v1.PrometheusRule{Spec: v1.PrometheusRuleSpec{Groups: []v1.RuleGroup{{Name: "test", Rules: c.rules}}}}
+		err := ValidatePrometheusRule(rule)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("%s: error = %v, want %q", c.name, err, c.want)
+		}
+	}
+
+	// 同名告警的label不同是常见的分级写法
+	rule := &v1.PrometheusRule{Spec: v1.PrometheusRuleSpec{Groups: []v1.RuleGroup{{Name: "test", Rules: []v1.Rule{
+		{Alert: "Down", Expr: intstr.FromString("up == 0"), For: "5m", Labels: map[string]string{"severity": "warning"}},
+		{Alert: "Down", Expr: intstr.FromString("up == 0"), For: "15m", Labels: map[string]string{"severity": "critical"}},
+	}}}}}
+	if err := ValidatePrometheusRule(rule); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,172 @@
+package v1
+
+import (
+	"fmt"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// 校验PrometheusRule,返回所有错误的集合:
+// expr能被PromQL解析,interval和for是合法的duration,label和annotation的模板能被解析,
+// record是合法的指标名,同一个PrometheusRule中没有名称和label都相同的告警
+func ValidatePrometheusRule(rule *v1.PrometheusRule) error {
+	var errs []error
+	groups := make(map[string]bool, len(rule.Spec.Groups))
+	alerts := make(map[string]string)
+
+	for _, group := range rule.Spec.Groups {
+		if group.Name == "" {
+			errs = append(errs, fmt.Errorf("group name is empty"))
+		} else if groups[group.Name] {
+			errs = append(errs, fmt.Errorf("group %s: duplicate group name", group.Name))
+		}
+		groups[group.Name] = true
+
+		if group.Interval != "" {
+			if _, err := model.ParseDuration(group.Interval); err != nil {
+				errs = append(errs, fmt.Errorf("group %s: invalid interval %q: %v", group.Name, group.Interval, err))
+			}
+		}
+
+		for i, r := range group.Rules {
+			for _, err := range validateRule(r) {
+				errs = append(errs, fmt.Errorf("group %s: %s: %v", group.Name, ruleName(r, i), err))
+			}
+
+			if r.Alert != "" {
+				key := r.Alert + "{" + formatLabels(r.Labels) + "}"
+				if previous, ok := alerts[key]; ok {
+					errs = append(errs, fmt.Errorf("group %s: alert %s: duplicate of alert in group %s", group.Name, r.Alert, previous))
+				} else {
+					alerts[key] = group.Name
+				}
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func validateRule(r v1.Rule) []error {
+	var errs []error
+	if (r.Record == "") == (r.Alert == "") {
+		errs = append(errs, fmt.Errorf("exactly one of record and alert must be set"))
+	}
+
+	expr := r.Expr.String()
+	if r.Expr == (intstr.IntOrString{}) || expr == "" {
+		errs = append(errs, fmt.Errorf("expr is empty"))
+	} else if _, err := parser.ParseExpr(expr); err != nil {
+		errs = append(errs, fmt.Errorf("invalid expr %q: %v", expr, err))
+	}
+
+	if r.Record != "" {
+		if !model.IsValidMetricName(model.LabelValue(r.Record)) {
+			errs = append(errs, fmt.Errorf("invalid recording rule name %q", r.Record))
+		}
+		if r.For != "" {
+			errs = append(errs, fmt.Errorf("for is only allowed in alerting rules"))
+		}
+		if len(r.Annotations) > 0 {
+			errs = append(errs, fmt.Errorf("annotations are only allowed in alerting rules"))
+		}
+	}
+
+	if r.For != "" {
+		if _, err := model.ParseDuration(r.For); err != nil {
+			errs = append(errs, fmt.Errorf("invalid for %q: %v", r.For, err))
+		}
+	}
+
+	for name := range r.Labels {
+		if !model.LabelName(name).IsValid() {
+			errs = append(errs, fmt.Errorf("invalid label name %q", name))
+		}
+	}
+	for name := range r.Annotations {
+		if !model.LabelName(name).IsValid() {
+			errs = append(errs, fmt.Errorf("invalid annotation name %q", name))
+		}
+	}
+
+	if r.Alert != "" {
+		errs = append(errs, validateTemplates(r)...)
+	}
+	return errs
+}
+
+// Prometheus告警模板中可以使用的函数,只用于解析,不会被调用.
+// prometheus/template会引入promql的测试工具,所以不直接使用
+var alertTemplateFuncs = func() template.FuncMap {
+	funcs := template.FuncMap{}
+	for _, name := range []string{
+		"query", "first", "label", "value", "strvalue", "args", "reReplaceAll", "safeHtml",
+		"match", "title", "toUpper", "toLower", "graphLink", "tableLink", "sortByLabel",
+		"humanize", "humanize1024", "humanizeDuration", "humanizePercentage", "humanizeTimestamp",
+		"pathPrefix", "externalURL",
+	} {
+		funcs[name] = func(args ...interface{}) interface{} { return nil }
+	}
+	return funcs
+}()
+
+// validateTemplates 和Prometheus加载规则时一样解析label和annotation中的模板
+func validateTemplates(r v1.Rule) []error {
+	defs := []string{
+		"{{$labels := .Labels}}",
+		"{{$externalLabels := .ExternalLabels}}",
+		"{{$value := .Value}}",
+	}
+	parse := func(text string) error {
+		_, err := template.New("__alert_" + r.Alert).
+			Funcs(alertTemplateFuncs).
+			Option("missingkey=zero").
+			Parse(strings.Join(append(defs, text), ""))
+		return err
+	}
+
+	var errs []error
+	for _, k := range sortedKeys(r.Labels) {
+		if err := parse(r.Labels[k]); err != nil {
+			errs = append(errs, fmt.Errorf("label %q: %v", k, err))
+		}
+	}
+	for _, k := range sortedKeys(r.Annotations) {
+		if err := parse(r.Annotations[k]); err != nil {
+			errs = append(errs, fmt.Errorf("annotation %q: %v", k, err))
+		}
+	}
+	return errs
+}
+
+func ruleName(r v1.Rule, index int) string {
+	switch {
+	case r.Alert != "":
+		return "alert " + r.Alert
+	case r.Record != "":
+		return "record " + r.Record
+	}
+	return fmt.Sprintf("rule %d", index)
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
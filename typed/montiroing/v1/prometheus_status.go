@@ -0,0 +1,283 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+)
+
+// PrometheusContainerState 容器的状态,State为Running,Waiting或Terminated
+type PrometheusContainerState struct {
+	Name         string `json:"name"`
+	Ready        bool   `json:"ready"`
+	State        string `json:"state"`
+	Reason       string `json:"reason,omitempty"`
+	RestartCount int32  `json:"restartCount"`
+}
+
+type PrometheusPodStatus struct {
+	Name       string                     `json:"name"`
+	Phase      coreV1.PodPhase            `json:"phase"`
+	Ready      bool                       `json:"ready"`
+	Containers []PrometheusContainerState `json:"containers"`
+}
+
+// PrometheusStatus Prometheus的运行状态和抓取目标的健康情况,
+// ServiceMonitors,PodMonitors和Rules为选中对象的namespace/name
+type PrometheusStatus struct {
+	Name            string                `json:"name"`
+	Namespace       string                `json:"namespace"`
+	Replicas        int32                 `json:"replicas"`
+	ReadyReplicas   int32                 `json:"readyReplicas"`
+	Pods            []PrometheusPodStatus `json:"pods"`
+	ServiceMonitors []string              `json:"serviceMonitors"`
+	PodMonitors     []string              `json:"podMonitors"`
+	Rules           []string              `json:"rules"`
+	// 每个job处于up状态和down状态的target数
+	UpTargets   map[string]int `json:"upTargets"`
+	DownTargets map[string]int `json:"downTargets"`
+	// 查询targets失败的原因,Prometheus不可用时其他字段仍然有效
+	TargetsErr error `json:"-"`
+}
+
+// 所有副本ready,target可以查询且没有down的target
+func (s *PrometheusStatus) Healthy() bool {
+	if s.ReadyReplicas < s.Replicas || s.TargetsErr != nil {
+		return false
+	}
+	for _, down := range s.DownTargets {
+		if down > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 汇总operator创建的StatefulSet的副本数,pod中容器的状态,按Prometheus的selector选中的
+// ServiceMonitor,PodMonitor和PrometheusRule,以及通过查询API得到的每个job的target健康情况
+func (p *prometheuses) Status(ctx context.Context, name string) (*PrometheusStatus, error) {
+	prom, err := p.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	status := &PrometheusStatus{
+		Name:      prom.Name,
+		Namespace: prom.Namespace,
+		Replicas:  1,
+	}
+	if prom.Spec.Replicas != nil {
+		status.Replicas = *prom.Spec.Replicas
+	}
+
+	sts, err := p.kubeClient.AppsV1().StatefulSets(p.ns).Get(ctx, prometheusStatefulSetName(name), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	status.ReadyReplicas = sts.Status.ReadyReplicas
+	if status.Pods, err = p.podStatuses(ctx, sts); err != nil {
+		return nil, err
+	}
+
+	if status.ServiceMonitors, err = p.selected(ctx, prom.Spec.ServiceMonitorSelector, prom.Spec.ServiceMonitorNamespaceSelector, func(ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+		list, err := p.client.MonitoringV1().ServiceMonitors(ns).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		objs := make([]metav1.Object, 0, len(list.Items))
+		for _, item := range list.Items {
+			objs = append(objs, item)
+		}
+		return objs, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if status.PodMonitors, err = p.selected(ctx, prom.Spec.PodMonitorSelector, prom.Spec.PodMonitorNamespaceSelector, func(ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+		list, err := p.client.MonitoringV1().PodMonitors(ns).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		objs := make([]metav1.Object, 0, len(list.Items))
+		for _, item := range list.Items {
+			objs = append(objs, item)
+		}
+		return objs, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if status.Rules, err = p.selected(ctx, prom.Spec.RuleSelector, prom.Spec.RuleNamespaceSelector, func(ns string, opts metav1.ListOptions) ([]metav1.Object, error) {
+		list, err := p.client.MonitoringV1().PrometheusRules(ns).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		objs := make([]metav1.Object, 0, len(list.Items))
+		for _, item := range list.Items {
+			objs = append(objs, item)
+		}
+		return objs, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var targets promv1.TargetsResult
+	client, err := p.instanceClient(prom, status.Pods)
+	if err == nil {
+		if targets, err = promv1.NewAPI(client).Targets(ctx); err == nil {
+			status.UpTargets, status.DownTargets = targetHealthByJob(targets.Active)
+		}
+	}
+	status.TargetsErr = err
+	return status, nil
+}
+
+// instanceClient 通过pod proxy访问这个Prometheus的一个ready pod
+func (p *prometheuses) instanceClient(prom *v1.Prometheus, pods []PrometheusPodStatus) (api.Client, error) {
+	if prom.Spec.ListenLocal {
+		return nil, fmt.Errorf("prometheus %s/%s listens on localhost only", p.ns, prom.Name)
+	}
+	pod := readyPod(pods)
+	if pod == "" {
+		return nil, fmt.Errorf("prometheus %s/%s has no ready pod", p.ns, prom.Name)
+	}
+	return podProxyClient(p.kubeClient, p.restConfig, p.ns, pod, prometheusWebPortNumber, prom.Spec.RoutePrefix)
+}
+
+// readyPod 返回第一个ready的pod名称
+func readyPod(pods []PrometheusPodStatus) string {
+	for _, pod := range pods {
+		if pod.Ready {
+			return pod.Name
+		}
+	}
+	return ""
+}
+
+func (p *prometheuses) podStatuses(ctx context.Context, sts *appsV1.StatefulSet) ([]PrometheusPodStatus, error) {
+	selector, err := metav1.LabelSelectorAsSelector(sts.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := p.kubeClient.CoreV1().Pods(p.ns).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]PrometheusPodStatus, 0, len(pods.Items))
+	for i := range pods.Items {
+		statuses = append(statuses, podStatus(&pods.Items[i]))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// selected 和operator一样计算selector选中的对象:selector为nil时不选中任何对象,
+// namespaceSelector为nil时只在Prometheus所在的namespace中查找
+func (p *prometheuses) selected(ctx context.Context, selector, namespaceSelector *metav1.LabelSelector, list func(ns string, opts metav1.ListOptions) ([]metav1.Object, error)) ([]string, error) {
+	if selector == nil {
+		return []string{}, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := p.selectedNamespaces(ctx, namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, ns := range namespaces {
+		objs, err := list(ns, metav1.ListOptions{LabelSelector: s.String()})
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			names = append(names, obj.GetNamespace()+"/"+obj.GetName())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (p *prometheuses) selectedNamespaces(ctx context.Context, namespaceSelector *metav1.LabelSelector) ([]string, error) {
+	if namespaceSelector == nil {
+		return []string{p.ns}, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	if s.Empty() {
+		return []string{metav1.NamespaceAll}, nil
+	}
+
+	list, err := p.kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: s.String()})
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+	return namespaces, nil
+}
+
+func podStatus(pod *coreV1.Pod) PrometheusPodStatus {
+	status := PrometheusPodStatus{
+		Name:  pod.Name,
+		Phase: pod.Status.Phase,
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == coreV1.PodReady {
+			status.Ready = c.Status == coreV1.ConditionTrue
+		}
+	}
+
+	for _, c := range pod.Status.ContainerStatuses {
+		state := PrometheusContainerState{
+			Name:         c.Name,
+			Ready:        c.Ready,
+			RestartCount: c.RestartCount,
+		}
+		switch {
+		case c.State.Running != nil:
+			state.State = "Running"
+		case c.State.Waiting != nil:
+			state.State, state.Reason = "Waiting", c.State.Waiting.Reason
+		case c.State.Terminated != nil:
+			state.State, state.Reason = "Terminated", c.State.Terminated.Reason
+		}
+		status.Containers = append(status.Containers, state)
+	}
+	return status
+}
+
+// targetHealthByJob 按job统计up和down的target数,unknown状态的target还没有被抓取过,不计入
+func targetHealthByJob(targets []promv1.ActiveTarget) (map[string]int, map[string]int) {
+	up := make(map[string]int)
+	down := make(map[string]int)
+	for _, t := range targets {
+		job := string(t.Labels["job"])
+		switch t.Health {
+		case promv1.HealthGood:
+			up[job]++
+		case promv1.HealthBad:
+			down[job]++
+		}
+	}
+	return up, down
+}
+
+// operator为Prometheus创建的StatefulSet
+func prometheusStatefulSetName(name string) string {
+	return fmt.Sprintf("prometheus-%s", name)
+}
@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"errors"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestTargetHealthByJob(t *testing.T) {
+	target := func(job string, health promv1.HealthStatus) promv1.ActiveTarget {
+		return promv1.ActiveTarget{Labels: model.LabelSet{"job": model.LabelValue(job)}, Health: health}
+	}
+	up, down := targetHealthByJob([]promv1.ActiveTarget{
+		target("app-forum", promv1.HealthGood),
+		target("app-forum", promv1.HealthBad),
+		target("app-forum", promv1.HealthBad),
+		target("node-exporter", promv1.HealthGood),
+		target("kubelet", promv1.HealthUnknown),
+	})
+
+	if up["app-forum"] != 1 || up["node-exporter"] != 1 || len(up) != 2 {
+		t.Fatalf("up = %v", up)
+	}
+	if down["app-forum"] != 2 || len(down) != 1 {
+		t.Fatalf("down = %v", down)
+	}
+}
+
+func TestPodStatus(t *testing.T) {
+	pod := &coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "prometheus-k8s-0"},
+		Status: coreV1.PodStatus{
+			Phase:      coreV1.PodRunning,
+			Conditions: []coreV1.PodCondition{{Type: coreV1.PodReady, Status: coreV1.ConditionFalse}},
+			ContainerStatuses: []coreV1.ContainerStatus{
+				{Name: "prometheus", State: coreV1.ContainerState{Waiting: &coreV1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}, RestartCount: 5},
+				{Name: "config-reloader", Ready: true, State: coreV1.ContainerState{Running: &coreV1.ContainerStateRunning{}}},
+			},
+		},
+	}
+
+	status := podStatus(pod)
+	if status.Ready || status.Phase != coreV1.PodRunning || len(status.Containers) != 2 {
+		t.Fatalf("status = %+v", status)
+	}
+	if c := status.Containers[0]; c.State != "Waiting" || c.Reason != "CrashLoopBackOff" || c.RestartCount != 5 {
+		t.Fatalf("prometheus container = %+v", c)
+	}
+	if c := status.Containers[1]; c.State != "Running" || !c.Ready {
+		t.Fatalf("config-reloader container = %+v", c)
+	}
+}
+
+func TestPrometheusStatusHealthy(t *testing.T) {
+	status := &PrometheusStatus{Replicas: 2, ReadyReplicas: 2, DownTargets: map[string]int{}}
+	if !status.Healthy() {
+		t.Fatal("expected healthy")
+	}
+
+	status.DownTargets["app-forum"] = 1
+	if status.Healthy() {
+		t.Fatal("down target should be unhealthy")
+	}
+
+	status.DownTargets = nil
+	status.TargetsErr = errors.New("connection refused")
+	if status.Healthy() {
+		t.Fatal("targets error should be unhealthy")
+	}
+
+	status.TargetsErr = nil
+	status.ReadyReplicas = 1
+	if status.Healthy() {
+		t.Fatal("unready replica should be unhealthy")
+	}
+}
+
+func TestReadyPod(t *testing.T) {
+	pods := []PrometheusPodStatus{{Name: "prometheus-k8s-0"}, {Name: "prometheus-k8s-1", Ready: true}}
+	if got := readyPod(pods); got != "prometheus-k8s-1" {
+		t.Fatalf("ready pod = %s", got)
+	}
+	if got := readyPod(pods[:1]); got != "" {
+		t.Fatalf("ready pod = %s", got)
+	}
+}
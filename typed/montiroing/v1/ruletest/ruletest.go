@@ -0,0 +1,287 @@
+// Package ruletest 使用Prometheus的promql测试工具在进程内计算PrometheusRule,
+// 依赖testing和测试存储,所以和monitoring client分开,只在需要时引入
+package ruletest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	k8sMonitoring "github.com/vperson/k8s-client/typed/montiroing/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultRuleTestInterval = time.Minute
+
+// Series 输入的时间序列,使用promtool的写法,
+// 例如Series为`up{job="app-forum"}`,Values为`1 1 0x10`
+type Series struct {
+	Series string
+	Values string
+}
+
+// ExpectedAlert 期望处于firing状态的告警,Labels不需要包含alertname,
+// Annotations为模板展开之后的内容
+type ExpectedAlert struct {
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// AlertTestCase 在EvalTime时名为Alertname的告警应该只有ExpAlerts,ExpAlerts为空表示不应该告警
+type AlertTestCase struct {
+	EvalTime  time.Duration
+	Alertname string
+	ExpAlerts []ExpectedAlert
+}
+
+// RuleTest 对应promtool test rules中的一个测试组
+type RuleTest struct {
+	// 输入序列中相邻两个值的间隔,默认1m
+	Interval    time.Duration
+	InputSeries []Series
+	AlertTests  []AlertTestCase
+	// 规则的计算间隔,默认1m
+	EvaluationInterval time.Duration
+	ExternalLabels     map[string]string
+}
+
+// 不依赖集群,在进程内用Prometheus的规则引擎计算PrometheusRule,
+// 和promtool test rules一样检查每个EvalTime的告警,返回所有不符合期望的结果
+func Run(rule *v1.PrometheusRule, test RuleTest) (err error) {
+	if err := k8sMonitoring.ValidatePrometheusRule(rule); err != nil {
+		return err
+	}
+
+	interval := test.Interval
+	if interval <= 0 {
+		interval = defaultRuleTestInterval
+	}
+	evalInterval := test.EvaluationInterval
+	if evalInterval <= 0 {
+		evalInterval = defaultRuleTestInterval
+	}
+
+	suite, err := newLazyLoader(seriesLoadingString(interval, test.InputSeries))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeLazyLoader(suite); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	opts := &rules.ManagerOptions{
+		QueryFunc:  rules.EngineQueryFunc(suite.QueryEngine(), suite.Storage()),
+		Appendable: suite.Storage(),
+		Context:    context.Background(),
+		NotifyFunc: func(ctx context.Context, expr string, alerts ...*rules.Alert) {},
+		Logger:     log.NewNopLogger(),
+	}
+	groups, err := ruleGroups(rule, evalInterval, labels.FromMap(test.ExternalLabels), opts)
+	if err != nil {
+		return err
+	}
+
+	tests := make(map[time.Duration][]AlertTestCase)
+	var maxEvalTime time.Duration
+	for _, tc := range test.AlertTests {
+		tests[tc.EvalTime] = append(tests[tc.EvalTime], tc)
+		if tc.EvalTime > maxEvalTime {
+			maxEvalTime = tc.EvalTime
+		}
+	}
+	evalTimes := make([]time.Duration, 0, len(tests))
+	for t := range tests {
+		evalTimes = append(evalTimes, t)
+	}
+	sort.Slice(evalTimes, func(i, j int) bool { return evalTimes[i] < evalTimes[j] })
+
+	var errs []error
+	mint := time.Unix(0, 0).UTC()
+	maxt := mint.Add(maxEvalTime + evalInterval)
+	next := 0
+	for ts := mint; ts.Before(maxt); ts = ts.Add(evalInterval) {
+		suite.WithSamplesTill(ts, func(err error) {
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			for _, g := range groups {
+				g.Eval(suite.Context(), ts)
+				for _, r := range g.Rules() {
+					if r.LastError() != nil {
+						errs = append(errs, fmt.Errorf("rule %s at %s: %v", r.Name(), ts.Sub(mint), r.LastError()))
+					}
+				}
+			}
+		})
+		if len(errs) > 0 {
+			return utilerrors.NewAggregate(errs)
+		}
+
+		// EvalTime落在[ts, ts+evalInterval)时使用ts的计算结果
+		for next < len(evalTimes) && evalTimes[next] < ts.Add(evalInterval).Sub(mint) {
+			for _, tc := range tests[evalTimes[next]] {
+				if err := checkAlerts(groups, tc); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			next++
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ruleTestT 实现testutil.T,LazyLoader在创建或关闭存储失败时调用Fatalf,
+// 通过panic中断调用,再由catchRuleTestFailure转换为错误
+type ruleTestT struct{}
+
+type ruleTestFailure struct {
+	err error
+}
+
+func (ruleTestT) Fatal(args ...interface{}) {
+	panic(ruleTestFailure{err: errors.New(fmt.Sprint(args...))})
+}
+
+func (ruleTestT) Fatalf(format string, args ...interface{}) {
+	panic(ruleTestFailure{err: fmt.Errorf(format, args...)})
+}
+
+func catchRuleTestFailure(err *error) {
+	if r := recover(); r != nil {
+		failure, ok := r.(ruleTestFailure)
+		if !ok {
+			panic(r)
+		}
+		*err = failure.err
+	}
+}
+
+func newLazyLoader(input string) (ll *promql.LazyLoader, err error) {
+	defer catchRuleTestFailure(&err)
+
+	ll, err = promql.NewLazyLoader(ruleTestT{}, input)
+	if err != nil && ll != nil {
+		// 解析失败时存储已经创建,需要关闭
+		closeLazyLoader(ll)
+		return nil, err
+	}
+	return ll, err
+}
+
+func closeLazyLoader(ll *promql.LazyLoader) (err error) {
+	defer catchRuleTestFailure(&err)
+
+	ll.Close()
+	return nil
+}
+
+// ruleGroups 将PrometheusRule转换为规则引擎的Group,保持原来的顺序
+func ruleGroups(rule *v1.PrometheusRule, evalInterval time.Duration, externalLabels labels.Labels, opts *rules.ManagerOptions) ([]*rules.Group, error) {
+	file := rule.Namespace + "/" + rule.Name
+	groups := make([]*rules.Group, 0, len(rule.Spec.Groups))
+	for _, group := range rule.Spec.Groups {
+		interval := evalInterval
+		if group.Interval != "" {
+			d, err := model.ParseDuration(group.Interval)
+			if err != nil {
+				return nil, err
+			}
+			interval = time.Duration(d)
+		}
+
+		var rs []rules.Rule
+		for _, r := range group.Rules {
+			expr, err := parser.ParseExpr(r.Expr.String())
+			if err != nil {
+				return nil, err
+			}
+
+			if r.Record != "" {
+				rs = append(rs, rules.NewRecordingRule(r.Record, expr, labels.FromMap(r.Labels)))
+				continue
+			}
+
+			var hold model.Duration
+			if r.For != "" {
+				if hold, err = model.ParseDuration(r.For); err != nil {
+					return nil, err
+				}
+			}
+			rs = append(rs, rules.NewAlertingRule(
+				r.Alert, expr, time.Duration(hold),
+				labels.FromMap(r.Labels), labels.FromMap(r.Annotations), externalLabels,
+				true, log.NewNopLogger(),
+			))
+		}
+
+		groups = append(groups, rules.NewGroup(rules.GroupOptions{
+			Name:     group.Name,
+			File:     file,
+			Interval: interval,
+			Rules:    rs,
+			Opts:     opts,
+		}))
+	}
+	return groups, nil
+}
+
+// checkAlerts 比较firing的告警,同名告警可以出现在多个组中
+func checkAlerts(groups []*rules.Group, tc AlertTestCase) error {
+	var got []string
+	for _, g := range groups {
+		for _, r := range g.Rules() {
+			ar, ok := r.(*rules.AlertingRule)
+			if !ok || ar.Name() != tc.Alertname {
+				continue
+			}
+			for _, a := range ar.ActiveAlerts() {
+				if a.State == rules.StateFiring {
+					got = append(got, formatAlert(a.Labels, a.Annotations))
+				}
+			}
+		}
+	}
+
+	want := make([]string, 0, len(tc.ExpAlerts))
+	for _, a := range tc.ExpAlerts {
+		lset := make(map[string]string, len(a.Labels)+1)
+		for k, v := range a.Labels {
+			lset[k] = v
+		}
+		lset[labels.AlertName] = tc.Alertname
+		want = append(want, formatAlert(labels.FromMap(lset), labels.FromMap(a.Annotations)))
+	}
+
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		return fmt.Errorf("alert %s at %s:\n  want: %v\n  got:  %v", tc.Alertname, tc.EvalTime, want, got)
+	}
+	return nil
+}
+
+func formatAlert(lset, annotations labels.Labels) string {
+	return "labels:" + lset.String() + " annotations:" + annotations.String()
+}
+
+// seriesLoadingString 生成promql测试的load命令
+func seriesLoadingString(interval time.Duration, series []Series) string {
+	var b strings.Builder
+	b.WriteString("load " + model.Duration(interval).String() + "\n")
+	for _, s := range series {
+		b.WriteString("  " + s.Series + " " + s.Values + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package ruletest
+
+import (
+	k8sMonitoring "github.com/vperson/k8s-client/typed/montiroing/v1"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	rule, err := k8sMonitoring.NewPrometheusRule("monitoring", "app-forum").
+		Group("app-forum.rules").
+		Record("job:up:sum", `sum by (job) (up)`).
+		Alert("InstanceDown", `up == 0`).For("5m").
+		Label("severity", "critical").
+		Annotation("summary", "{{ $labels.instance }} of {{ $labels.job }} is down").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := RuleTest{
+		InputSeries: []Series{
+			{Series: `up{job="app-forum", instance="10.0.0.1:8080"}`, Values: `1 1 0x10`},
+			{Series: `up{job="app-forum", instance="10.0.0.2:8080"}`, Values: `1x12`},
+		},
+		AlertTests: []AlertTestCase{
+			// 2分钟开始为0,for 5m之前处于pending
+			{EvalTime: 5 * time.Minute, Alertname: "InstanceDown"},
+			{EvalTime: 8 * time.Minute, Alertname: "InstanceDown", ExpAlerts: []ExpectedAlert{{
+				Labels:      map[string]string{"job": "app-forum", "instance": "10.0.0.1:8080", "severity": "critical"},
+				Annotations: map[string]string{"summary": "10.0.0.1:8080 of app-forum is down"},
+			}}},
+		},
+	}
+	if err := Run(rule, test); err != nil {
+		t.Fatal(err)
+	}
+
+	test.AlertTests = []AlertTestCase{{EvalTime: 8 * time.Minute, Alertname: "InstanceDown"}}
+	err = Run(rule, test)
+	if err == nil || !strings.Contains(err.Error(), "alert InstanceDown at 8m0s") {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+}
+
+func TestNewLazyLoaderStorageFailure(t *testing.T) {
+	tmp := os.Getenv("TMPDIR")
+	defer os.Setenv("TMPDIR", tmp)
+	os.Setenv("TMPDIR", "/nonexistent/k8s-client")
+
+	ll, err := newLazyLoader("load 1m\n  up 1 1 1\n")
+	if err == nil || ll != nil {
+		t.Fatalf("expected storage error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Opening test dir failed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/coreos/prometheus-operator/pkg/client/versioned"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 )
 
@@ -20,7 +21,8 @@ type ServiceMonitorInterface interface {
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.ServiceMonitor, error)
 	List(ctx context.Context, opts metav1.ListOptions) (*v1.ServiceMonitorList, error)
 	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
-	//Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (result *v1.ServiceMonitor, err error)
+	WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan ServiceMonitorEvent
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.ServiceMonitor, error)
 }
 
 type serviceMonitors struct {
@@ -70,3 +72,9 @@ func (s *serviceMonitors) Watch(ctx context.Context, opts metav1.ListOptions) (w
 		ServiceMonitors(s.ns).
 		Watch(ctx, opts)
 }
+
+func (s *serviceMonitors) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.ServiceMonitor, error) {
+	return s.client.MonitoringV1().
+		ServiceMonitors(s.ns).
+		Patch(ctx, name, pt, data, opts, subresources...)
+}
@@ -0,0 +1,319 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"strconv"
+	"time"
+)
+
+// 和Prometheus kubernetes_sd示例配置一致的Service annotation,interval为扩展
+const (
+	ScrapeAnnotation         = "prometheus.io/scrape"
+	ScrapePortAnnotation     = "prometheus.io/port"
+	ScrapePathAnnotation     = "prometheus.io/path"
+	ScrapeSchemeAnnotation   = "prometheus.io/scheme"
+	ScrapeIntervalAnnotation = "prometheus.io/interval"
+
+	serviceMonitorMaxRetries = 5
+)
+
+type ServiceMonitorControllerOptions struct {
+	// 生成的ServiceMonitor使用这个Prometheus的serviceMonitorSelector能选中的label,
+	// Prometheus的serviceMonitorNamespaceSelector也需要选中Service所在的namespace
+	PrometheusNamespace string
+	PrometheusName      string
+	// 只处理这个namespace中的Service,为空时处理所有namespace
+	Namespace string
+}
+
+// ServiceMonitorController 为带有prometheus.io/scrape: "true" annotation的Service维护同名的ServiceMonitor.
+// ServiceMonitor的ownerReferences指向Service,Service删除后由垃圾回收删除,
+// 去掉annotation后删除ServiceMonitor.已经存在且不属于Service的同名ServiceMonitor不会被修改
+type ServiceMonitorController struct {
+	monitoring *PrometheusMonitoring
+	labels     map[string]string
+	ns         string
+
+	indexer  cache.Indexer
+	queue    workqueue.RateLimitingInterface
+	informer cache.Controller
+}
+
+// 创建ServiceMonitor控制器,ServiceMonitor的label在创建时根据Prometheus的serviceMonitorSelector计算
+func (c *PrometheusMonitoring) ServiceMonitorController(ctx context.Context, opts ServiceMonitorControllerOptions) (*ServiceMonitorController, error) {
+	prom, err := c.Prometheuses(opts.PrometheusNamespace).Get(ctx, opts.PrometheusName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	monitorLabels, err := serviceMonitorSelectorLabels(prom)
+	if err != nil {
+		return nil, err
+	}
+
+	controller := &ServiceMonitorController{
+		monitoring: c,
+		labels:     monitorLabels,
+		ns:         opts.Namespace,
+		queue:      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+
+	serviceListWatcher := cache.NewListWatchFromClient(c.kubeClient.CoreV1().RESTClient(), "services", opts.Namespace, fields.Everything())
+	controller.indexer, controller.informer = cache.NewIndexerInformer(serviceListWatcher, &coreV1.Service{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if scrapeEnabled(obj.(*coreV1.Service)) {
+				controller.enqueue(obj)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// 去掉annotation时也需要处理,删除ServiceMonitor
+			if scrapeEnabled(oldObj.(*coreV1.Service)) || scrapeEnabled(newObj.(*coreV1.Service)) {
+				controller.enqueue(newObj)
+			}
+		},
+	}, cache.Indexers{})
+
+	return controller, nil
+}
+
+// 阻塞直到ctx结束.除了Service的informer,还watch ServiceMonitor,
+// 被手动修改或删除的ServiceMonitor会重新同步,启动时也会清理控制器停止期间去掉annotation的Service的ServiceMonitor
+func (c *ServiceMonitorController) Run(ctx context.Context, threadNum int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("start servicemonitor controller")
+
+	go c.informer.Run(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.informer.HasSynced) {
+		runtime.HandleError(fmt.Errorf("time out waiting for caches to sync"))
+		return
+	}
+
+	go func() {
+		for event := range c.monitoring.ServiceMonitors(c.ns).WatchEvents(ctx, metav1.ListOptions{}) {
+			if owner := serviceOwner(event.Object); owner != "" {
+				c.queue.Add(event.Object.Namespace + "/" + owner)
+			}
+		}
+	}()
+
+	for i := 0; i < threadNum; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+	<-ctx.Done()
+	klog.Info("stopping servicemonitor controller")
+}
+
+func (c *ServiceMonitorController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err == nil {
+		c.queue.Add(key)
+	}
+}
+
+func (c *ServiceMonitorController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+
+	}
+}
+
+func (c *ServiceMonitorController) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+
+	defer c.queue.Done(key)
+
+	err := c.sync(ctx, key.(string))
+	c.handleErr(err, key)
+	return true
+}
+
+func (c *ServiceMonitorController) sync(ctx context.Context, key string) error {
+	obj, exists, err := c.indexer.GetByKey(key)
+	if err != nil {
+		return err
+	}
+	// Service删除后ServiceMonitor由垃圾回收删除
+	if !exists {
+		return nil
+	}
+	svc := obj.(*coreV1.Service)
+	serviceMonitors := c.monitoring.ServiceMonitors(svc.Namespace)
+
+	existing, err := serviceMonitors.Get(ctx, svc.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		existing, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing != nil && !metav1.IsControlledBy(existing, svc) {
+		if scrapeEnabled(svc) {
+			klog.Warningf("servicemonitor %s already exists and is not owned by the service, skipping", key)
+		}
+		return nil
+	}
+
+	if !scrapeEnabled(svc) {
+		if existing == nil {
+			return nil
+		}
+		klog.Infof("deleting servicemonitor %s: service is no longer annotated", key)
+		err := serviceMonitors.Delete(ctx, existing.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &existing.UID},
+		})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	desired, err := serviceMonitorForAnnotations(svc, c.labels)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		klog.Infof("creating servicemonitor %s", key)
+		_, err := serviceMonitors.Create(ctx, desired, metav1.CreateOptions{})
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(existing.Spec, desired.Spec) && equality.Semantic.DeepEqual(existing.Labels, desired.Labels) {
+		return nil
+	}
+	klog.Infof("updating servicemonitor %s", key)
+	updated := existing.DeepCopy()
+	updated.Labels = desired.Labels
+	updated.Spec = desired.Spec
+	_, err = serviceMonitors.Update(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *ServiceMonitorController) handleErr(err error, key interface{}) {
+	if err == nil {
+		c.queue.Forget(key)
+		return
+	}
+
+	if c.queue.NumRequeues(key) < serviceMonitorMaxRetries {
+		klog.Infof("error syncing servicemonitor %v: %v", key, err)
+		c.queue.AddRateLimited(key)
+		return
+	}
+
+	c.queue.Forget(key)
+	runtime.HandleError(err)
+	klog.Infof("dropping servicemonitor %q out of the queue: %v", key, err)
+}
+
+// 根据Service的prometheus.io annotation生成ServiceMonitor,ownerReferences指向Service
+func serviceMonitorForAnnotations(svc *coreV1.Service, monitorLabels map[string]string) (*v1.ServiceMonitor, error) {
+	opts := ServiceMonitorOptions{
+		Labels:   monitorLabels,
+		Path:     svc.Annotations[ScrapePathAnnotation],
+		Scheme:   svc.Annotations[ScrapeSchemeAnnotation],
+		Interval: svc.Annotations[ScrapeIntervalAnnotation],
+	}
+	annotatedTargetPort(svc, &opts)
+
+	sm, err := ServiceMonitorForService(svc, opts)
+	if err != nil {
+		return nil, err
+	}
+	sm.OwnerReferences = []metav1.OwnerReference{
+		*metav1.NewControllerRef(svc, coreV1.SchemeGroupVersion.WithKind("Service")),
+	}
+	return sm, nil
+}
+
+// annotatedTargetPort 设置采集的端口.和kubernetes_sd一样,端口号是pod的容器端口,不需要在Service中声明;
+// 端口名称引用Service的端口.没有annotation时采集所有命名的端口,
+// 只有一个未命名的端口时采集它的targetPort
+func annotatedTargetPort(svc *coreV1.Service, opts *ServiceMonitorOptions) {
+	port := svc.Annotations[ScrapePortAnnotation]
+	if port != "" {
+		if number, err := strconv.Atoi(port); err == nil {
+			targetPort := intstr.FromInt(number)
+			opts.TargetPort = &targetPort
+		} else {
+			opts.Ports = []string{port}
+		}
+		return
+	}
+
+	if len(svc.Spec.Ports) == 1 && svc.Spec.Ports[0].Name == "" {
+		p := svc.Spec.Ports[0]
+		targetPort := p.TargetPort
+		if targetPort.Type == intstr.Int && targetPort.IntVal == 0 {
+			targetPort = intstr.FromInt(int(p.Port))
+		}
+		opts.TargetPort = &targetPort
+	}
+}
+
+// serviceMonitorSelectorLabels 返回能被Prometheus的serviceMonitorSelector选中的label,
+// matchExpressions中In使用第一个值,Exists使用"true"
+func serviceMonitorSelectorLabels(prom *v1.Prometheus) (map[string]string, error) {
+	selector := prom.Spec.ServiceMonitorSelector
+	if selector == nil {
+		return nil, fmt.Errorf("prometheus %s/%s does not select any ServiceMonitor", prom.Namespace, prom.Name)
+	}
+
+	result := make(map[string]string, len(selector.MatchLabels))
+	for k, v := range selector.MatchLabels {
+		result[k] = v
+	}
+	for _, expr := range selector.MatchExpressions {
+		if _, ok := result[expr.Key]; ok {
+			continue
+		}
+		switch expr.Operator {
+		case metav1.LabelSelectorOpIn:
+			if len(expr.Values) > 0 {
+				result[expr.Key] = expr.Values[0]
+			}
+		case metav1.LabelSelectorOpExists:
+			result[expr.Key] = "true"
+		}
+	}
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Matches(labels.Set(result)) {
+		return nil, fmt.Errorf("no labels satisfy serviceMonitorSelector %s of prometheus %s/%s", s, prom.Namespace, prom.Name)
+	}
+	return result, nil
+}
+
+func scrapeEnabled(svc *coreV1.Service) bool {
+	return svc.Annotations[ScrapeAnnotation] == "true"
+}
+
+// serviceOwner 返回ServiceMonitor所属的Service名称
+func serviceOwner(sm *v1.ServiceMonitor) string {
+	owner := metav1.GetControllerOf(sm)
+	if owner == nil || owner.Kind != "Service" || owner.APIVersion != coreV1.SchemeGroupVersion.String() {
+		return ""
+	}
+	return owner.Name
+}
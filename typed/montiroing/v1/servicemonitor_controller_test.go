@@ -0,0 +1,175 @@
+package v1
+
+import (
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"regexp"
+	"testing"
+)
+
+func TestServiceMonitorForAnnotations(t *testing.T) {
+	svc := &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-forum",
+			Namespace: "dev1-xiaomai-server",
+			UID:       "1234",
+			Labels:    map[string]string{"app": "app-forum"},
+			Annotations: map[string]string{
+				ScrapeAnnotation:         "true",
+				ScrapePortAnnotation:     "9102",
+				ScrapePathAnnotation:     "/internal/metrics",
+				ScrapeIntervalAnnotation: "15s",
+			},
+		},
+		Spec: coreV1.ServiceSpec{
+			Ports: []coreV1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+				{Name: "metrics", Port: 9090, TargetPort: intstr.FromInt(9102)},
+			},
+		},
+	}
+
+	sm, err := serviceMonitorForAnnotations(svc, map[string]string{"prometheus": "k8s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm.Name != "app-forum" || sm.Labels["prometheus"] != "k8s" {
+		t.Fatalf("metadata = %+v", sm.ObjectMeta)
+	}
+	if len(sm.Spec.Endpoints) != 1 {
+		t.Fatalf("endpoints = %+v", sm.Spec.Endpoints)
+	}
+	if e := sm.Spec.Endpoints[0]; e.Port != "" || e.TargetPort == nil || e.TargetPort.IntValue() != 9102 || e.Path != "/internal/metrics" || e.Interval != "15s" {
+		t.Fatalf("endpoint = %+v", e)
+	}
+	if !metav1.IsControlledBy(sm, svc) || serviceOwner(sm) != "app-forum" {
+		t.Fatalf("owner references = %+v", sm.OwnerReferences)
+	}
+}
+
+// scrapedServices 和operator生成的配置一样,先按selector选择Service,再执行relabel的keep
+func scrapedServices(t *testing.T, sm *v1.ServiceMonitor, services []*coreV1.Service) []string {
+	selector, err := metav1.LabelSelectorAsSelector(&sm.Spec.Selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, svc := range services {
+		if !selector.Matches(labels.Set(svc.Labels)) {
+			continue
+		}
+		kept := true
+		for _, e := range sm.Spec.Endpoints {
+			for _, r := range e.RelabelConfigs {
+				if r.Action == "keep" && r.SourceLabels[0] == "__meta_kubernetes_service_name" &&
+					!regexp.MustCompile("^(?:"+r.Regex+")$").MatchString(svc.Name) {
+					kept = false
+				}
+			}
+		}
+		if kept {
+			names = append(names, svc.Name)
+		}
+	}
+	return names
+}
+
+func TestServiceMonitorForAnnotationsSharedLabels(t *testing.T) {
+	annotations := map[string]string{ScrapeAnnotation: "true", ScrapePortAnnotation: "9102"}
+	services := []*coreV1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Name: "app-forum", Namespace: "dev1-xiaomai-server", Labels: map[string]string{"app": "app-forum"}, Annotations: annotations}},
+		// 没有scrape annotation,label是app-forum的超集
+		{ObjectMeta: metav1.ObjectMeta{Name: "app-forum-headless", Namespace: "dev1-xiaomai-server", Labels: map[string]string{"app": "app-forum", "headless": "true"}}},
+		// 没有label的Service也能生成ServiceMonitor
+		{ObjectMeta: metav1.ObjectMeta{Name: "app-market", Namespace: "dev1-xiaomai-server", Annotations: annotations}},
+	}
+
+	for _, svc := range []*coreV1.Service{services[0], services[2]} {
+		sm, err := serviceMonitorForAnnotations(svc, map[string]string{"prometheus": "k8s"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := scrapedServices(t, sm, services); len(got) != 1 || got[0] != svc.Name {
+			t.Fatalf("servicemonitor %s scrapes %v", sm.Name, got)
+		}
+	}
+}
+
+func TestAnnotatedTargetPort(t *testing.T) {
+	svc := &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default", Labels: map[string]string{"app": "legacy"}},
+		Spec: coreV1.ServiceSpec{
+			Ports: []coreV1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	// 容器端口不需要在Service中声明
+	svc.Annotations = map[string]string{ScrapeAnnotation: "true", ScrapePortAnnotation: "9102"}
+	sm, err := serviceMonitorForAnnotations(svc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.Spec.Endpoints) != 1 || sm.Spec.Endpoints[0].TargetPort.IntValue() != 9102 {
+		t.Fatalf("endpoints = %+v", sm.Spec.Endpoints)
+	}
+
+	// 没有port annotation时采集唯一未命名端口的targetPort
+	svc.Annotations = map[string]string{ScrapeAnnotation: "true"}
+	if sm, err = serviceMonitorForAnnotations(svc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.Spec.Endpoints) != 1 || sm.Spec.Endpoints[0].TargetPort.IntValue() != 8080 {
+		t.Fatalf("endpoints = %+v", sm.Spec.Endpoints)
+	}
+
+	svc.Spec.Ports = []coreV1.ServicePort{{Name: "http", Port: 80}, {Name: "metrics", Port: 9090}}
+	svc.Annotations = map[string]string{ScrapeAnnotation: "true", ScrapePortAnnotation: "metrics"}
+	if sm, err = serviceMonitorForAnnotations(svc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.Spec.Endpoints) != 1 || sm.Spec.Endpoints[0].Port != "metrics" || sm.Spec.Endpoints[0].TargetPort != nil {
+		t.Fatalf("endpoints = %+v", sm.Spec.Endpoints)
+	}
+
+	svc.Annotations[ScrapePortAnnotation] = "grpc"
+	if _, err := serviceMonitorForAnnotations(svc, nil); err == nil {
+		t.Fatal("expected error for unknown port name")
+	}
+}
+
+func TestServiceMonitorSelectorLabels(t *testing.T) {
+	prom := &v1.Prometheus{ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "k8s"}}
+	if _, err := serviceMonitorSelectorLabels(prom); err == nil {
+		t.Fatal("expected error for nil selector")
+	}
+
+	prom.Spec.ServiceMonitorSelector = &metav1.LabelSelector{
+		MatchLabels: map[string]string{"prometheus": "k8s"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"infra", "app"}},
+			{Key: "monitored", Operator: metav1.LabelSelectorOpExists},
+			{Key: "legacy", Operator: metav1.LabelSelectorOpDoesNotExist},
+		},
+	}
+	got, err := serviceMonitorSelectorLabels(prom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got["prometheus"] != "k8s" || got["team"] != "infra" || got["monitored"] != "true" {
+		t.Fatalf("labels = %v", got)
+	}
+
+	prom.Spec.ServiceMonitorSelector = &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "team", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"infra"}},
+			{Key: "team", Operator: metav1.LabelSelectorOpExists},
+		},
+	}
+	if got, err := serviceMonitorSelectorLabels(prom); err != nil || got["team"] != "true" {
+		t.Fatalf("labels = %v, %v", got, err)
+	}
+}
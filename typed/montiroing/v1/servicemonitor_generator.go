@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"fmt"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"regexp"
+)
+
+type ServiceMonitorOptions struct {
+	// 为空时使用Service的名称
+	Name string
+	// ServiceMonitor的label,需要匹配Prometheus的serviceMonitorSelector
+	Labels map[string]string
+	// 只采集这些端口,为空时采集Service所有命名的端口
+	Ports []string
+	// 按pod的容器端口采集,端口可以不在Service中,设置后忽略Ports
+	TargetPort *intstr.IntOrString
+	// 采集路径,默认/metrics
+	Path string
+	// 采集间隔,例如30s,为空时使用Prometheus的全局配置
+	Interval string
+	Scheme   string
+}
+
+// 根据Service生成ServiceMonitor,只选择Service所在的namespace.
+// selector使用Service的全部label,label相同的其他Service(例如headless Service)也会被选中,
+// 所以每个endpoint再按__meta_kubernetes_service_name只保留这个Service.
+// ServiceMonitor通过端口名称引用端口,未命名的端口会被忽略
+func ServiceMonitorForService(svc *coreV1.Service, opts ServiceMonitorOptions) (*v1.ServiceMonitor, error) {
+	var endpoints []v1.Endpoint
+	if opts.TargetPort != nil {
+		targetPort := *opts.TargetPort
+		endpoints = append(endpoints, v1.Endpoint{
+			TargetPort:     &targetPort,
+			Path:           opts.Path,
+			Interval:       opts.Interval,
+			Scheme:         opts.Scheme,
+			RelabelConfigs: keepService(svc.Name),
+		})
+	} else {
+		ports, err := scrapePorts(svc, opts.Ports)
+		if err != nil {
+			return nil, err
+		}
+		for _, port := range ports {
+			endpoints = append(endpoints, v1.Endpoint{
+				Port:           port,
+				Path:           opts.Path,
+				Interval:       opts.Interval,
+				Scheme:         opts.Scheme,
+				RelabelConfigs: keepService(svc.Name),
+			})
+		}
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = svc.Name
+	}
+
+	selector := make(map[string]string, len(svc.Labels))
+	for k, v := range svc.Labels {
+		selector[k] = v
+	}
+	labels := make(map[string]string, len(opts.Labels))
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+
+	return &v1.ServiceMonitor{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1.SchemeGroupVersion.String(),
+			Kind:       v1.ServiceMonitorsKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: svc.Namespace,
+			Labels:    labels,
+		},
+		Spec: v1.ServiceMonitorSpec{
+			Endpoints:         endpoints,
+			Selector:          metav1.LabelSelector{MatchLabels: selector},
+			NamespaceSelector: v1.NamespaceSelector{MatchNames: []string{svc.Namespace}},
+		},
+	}, nil
+}
+
+// keepService 只保留名称为name的Service的target
+func keepService(name string) []*v1.RelabelConfig {
+	return []*v1.RelabelConfig{{
+		SourceLabels: []string{"__meta_kubernetes_service_name"},
+		Regex:        regexp.QuoteMeta(name),
+		Action:       "keep",
+	}}
+}
+
+// scrapePorts 返回需要采集的端口名称,指定的端口不存在或没有命名的端口时返回错误
+func scrapePorts(svc *coreV1.Service, wanted []string) ([]string, error) {
+	named := make(map[string]bool, len(svc.Spec.Ports))
+	var ports []string
+	for _, p := range svc.Spec.Ports {
+		if p.Name != "" {
+			named[p.Name] = true
+			ports = append(ports, p.Name)
+		}
+	}
+
+	if len(wanted) == 0 {
+		if len(ports) == 0 {
+			return nil, fmt.Errorf("service %s/%s has no named ports", svc.Namespace, svc.Name)
+		}
+		return ports, nil
+	}
+
+	for _, name := range wanted {
+		if !named[name] {
+			return nil, fmt.Errorf("service %s/%s has no port named %s", svc.Namespace, svc.Name, name)
+		}
+	}
+	return wanted, nil
+}
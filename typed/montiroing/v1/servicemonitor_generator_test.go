@@ -0,0 +1,54 @@
+package v1
+
+import (
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestServiceMonitorForService(t *testing.T) {
+	svc := &coreV1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-forum",
+			Namespace: "dev1-xiaomai-server",
+			Labels:    map[string]string{"app": "app-forum"},
+		},
+		Spec: coreV1.ServiceSpec{
+			Ports: []coreV1.ServicePort{
+				{Name: "http", Port: 80},
+				{Name: "metrics", Port: 9090},
+				{Port: 8080},
+			},
+		},
+	}
+
+	sm, err := ServiceMonitorForService(svc, ServiceMonitorOptions{
+		Labels:   map[string]string{"prometheus": "k8s"},
+		Ports:    []string{"metrics"},
+		Interval: "30s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm.Name != "app-forum" || sm.Namespace != "dev1-xiaomai-server" || sm.Labels["prometheus"] != "k8s" {
+		t.Fatalf("metadata = %+v", sm.ObjectMeta)
+	}
+	if sm.Spec.Selector.MatchLabels["app"] != "app-forum" {
+		t.Fatalf("selector = %+v", sm.Spec.Selector)
+	}
+	if len(sm.Spec.Endpoints) != 1 || sm.Spec.Endpoints[0].Port != "metrics" || sm.Spec.Endpoints[0].Interval != "30s" {
+		t.Fatalf("endpoints = %+v", sm.Spec.Endpoints)
+	}
+
+	all, err := ServiceMonitorForService(svc, ServiceMonitorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Spec.Endpoints) != 2 {
+		t.Fatalf("unnamed port should be ignored, endpoints = %+v", all.Spec.Endpoints)
+	}
+
+	if _, err := ServiceMonitorForService(svc, ServiceMonitorOptions{Ports: []string{"grpc"}}); err == nil {
+		t.Fatal("expected error for unknown port")
+	}
+}
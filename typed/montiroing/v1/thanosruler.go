@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"context"
+	v1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/coreos/prometheus-operator/pkg/client/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type ThanosRulersGetter interface {
+	ThanosRulers(namespace string) ThanosRulerInterface
+}
+
+type ThanosRulerInterface interface {
+	Create(ctx context.Context, thanosRuler *v1.ThanosRuler, opts metav1.CreateOptions) (*v1.ThanosRuler, error)
+	Update(ctx context.Context, thanosRuler *v1.ThanosRuler, opts metav1.UpdateOptions) (*v1.ThanosRuler, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	//DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.ThanosRuler, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1.ThanosRulerList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	WatchEvents(ctx context.Context, opts metav1.ListOptions) <-chan ThanosRulerEvent
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.ThanosRuler, error)
+}
+
+type thanosRulers struct {
+	client *versioned.Clientset
+	ns     string
+}
+
+func newThanosRulers(c *versioned.Clientset, namespace string) *thanosRulers {
+	return &thanosRulers{
+		client: c,
+		ns:     namespace,
+	}
+}
+
+func (t *thanosRulers) Create(ctx context.Context, thanosRuler *v1.ThanosRuler, opts metav1.CreateOptions) (*v1.ThanosRuler, error) {
+	return t.client.MonitoringV1().
+		ThanosRulers(t.ns).
+		Create(ctx, thanosRuler, opts)
+}
+
+func (t *thanosRulers) Update(ctx context.Context, thanosRuler *v1.ThanosRuler, opts metav1.UpdateOptions) (*v1.ThanosRuler, error) {
+	return t.client.MonitoringV1().
+		ThanosRulers(t.ns).
+		Update(ctx, thanosRuler, opts)
+}
+
+func (t *thanosRulers) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return t.client.MonitoringV1().
+		ThanosRulers(t.ns).
+		Delete(ctx, name, opts)
+}
+
+func (t *thanosRulers) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.ThanosRuler, error) {
+	return t.client.MonitoringV1().
+		ThanosRulers(t.ns).
+		Get(ctx, name, opts)
+}
+
+func (t *thanosRulers) List(ctx context.Context, opts metav1.ListOptions) (*v1.ThanosRulerList, error) {
+	return t.client.MonitoringV1().
+		ThanosRulers(t.ns).
+		List(ctx, opts)
+}
+
+func (t *thanosRulers) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return t.client.MonitoringV1().
+		ThanosRulers(t.ns).
+		Watch(ctx, opts)
+}
+
+func (t *thanosRulers) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1.ThanosRuler, error) {
+	return t.client.MonitoringV1().
+		ThanosRulers(t.ns).
+		Patch(ctx, name, pt, data, opts, subresources...)
+}